@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/url"
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/feeds"
 	"github.com/labstack/echo/v4"
 	"github.com/uphy/feedgen/config"
 	"github.com/uphy/feedgen/converter"
 	"github.com/uphy/feedgen/generator"
 	"github.com/uphy/feedgen/generator/browser"
+	"github.com/uphy/feedgen/generator/merge"
 	"github.com/uphy/feedgen/generator/template"
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/repo"
 	"github.com/urfave/cli/v2"
 )
@@ -26,6 +32,13 @@ type App struct {
 	repository    *repo.Repository
 	configFile    string
 	feedGenerator *generator.FeedGenerators
+	// scheduler holds the *scheduler of the running server, replaced on restart.
+	scheduler   atomic.Value
+	cache       *responseCache
+	browserPool *browser.Pool
+	// ready counts the servers serving the feeds.
+	// The stopping server and the new server overlap on restart, so it is not a flag.
+	ready int32
 }
 
 func New() *App {
@@ -33,6 +46,7 @@ func New() *App {
 	app := &App{
 		app: a,
 	}
+	app.cache = newResponseCache(app)
 
 	a.Flags = []cli.Flag{
 		&cli.StringFlag{
@@ -49,36 +63,104 @@ func New() *App {
 			Name:  "no-sandbox",
 			Value: false,
 		},
+		&cli.IntFlag{
+			Name:    "browser-pool-size",
+			EnvVars: []string{"FEED_GEN_BROWSER_POOL_SIZE"},
+			Value:   2,
+			Usage:   "Max number of the browser tabs open at the same time",
+		},
+		&cli.StringFlag{
+			Name:    "browser-url",
+			EnvVars: []string{"FEED_GEN_BROWSER_URL"},
+			Usage:   "DevTools URL of the remote browser to use instead of starting Chrome (ws://... or http://host:9222)",
+		},
+		&cli.DurationFlag{
+			Name:    "browser-idle-timeout",
+			EnvVars: []string{"FEED_GEN_BROWSER_IDLE_TIMEOUT"},
+			Value:   5 * time.Minute,
+			Usage:   "Duration to keep the browser running without any tab (0 means forever)",
+		},
+		&cli.StringFlag{
+			Name:    "base-url",
+			EnvVars: []string{"FEED_GEN_BASE_URL"},
+			Usage:   "Public URL of the server to build the absolute URLs of the media such as screenshots (relative if empty)",
+		},
+		&cli.StringFlag{
+			Name:    "store",
+			EnvVars: []string{"FEED_GEN_STORE"},
+			Value:   "badger://data",
+			Usage:   "Repository to store feeds (badger://<dir>, sqlite://<file> or memory://)",
+		},
+		&cli.IntFlag{
+			Name:    "max-concurrency",
+			EnvVars: []string{"FEED_GEN_MAX_CONCURRENCY"},
+			Value:   0,
+			Usage:   "Max number of the concurrent feed generations (0 means unlimited)",
+		},
 	}
 	a.Before = func(c *cli.Context) error {
+		app.configFile = c.String("config")
+		// the browser is started on demand
+		app.browserPool = browser.NewPool(browser.PoolOptions{
+			NoSandbox:   c.Bool("no-sandbox"),
+			Size:        c.Int("browser-pool-size"),
+			IdleTimeout: c.Duration("browser-idle-timeout"),
+			RemoteURL:   c.String("browser-url"),
+		})
+		// 'validate' reports the problems of the config file by itself
+		if c.Args().First() == "validate" {
+			return nil
+		}
+
 		// load repository
+		store := c.String("store")
 		if c.Bool("no-cache") {
-			app.repository = repo.NewMemoryRepository()
-		} else {
-			repository, err := repo.NewBadgerRepository("data")
-			if err != nil {
-				return err
-			}
-			app.repository = repository
+			store = "memory://"
 		}
+		repository, err := openRepository(store)
+		if err != nil {
+			return fmt.Errorf("failed to open repository: store=%s, err=%w", store, err)
+		}
+		app.repository = metrics.InstrumentRepository(repository)
 
 		// load config
-		configFile := c.String("config")
-		app.configFile = configFile
 		return app.reloadConfig(c)
 	}
 	a.After = func(c *cli.Context) error {
-		app.repository.Close()
+		if app.repository != nil {
+			app.repository.Close()
+		}
+		if app.browserPool != nil {
+			app.browserPool.Close()
+		}
 		return nil
 	}
 
 	a.Commands = []*cli.Command{
 		app.generateCommand(),
 		app.startServerCommand(),
+		app.validateCommand(),
 	}
 	return app
 }
 
+func openRepository(store string) (*repo.Repository, error) {
+	i := strings.Index(store, "://")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid store: %s", store)
+	}
+	scheme, path := store[:i], store[i+3:]
+	switch scheme {
+	case "badger":
+		return repo.NewBadgerRepository(path)
+	case "sqlite":
+		return repo.NewSQLiteRepository(path)
+	case "memory":
+		return repo.NewMemoryRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported store: %s", scheme)
+}
+
 func (a *App) reloadConfig(c *cli.Context) error {
 	// load config
 	cnf, err := config.ParseConfig(a.configFile)
@@ -86,12 +168,7 @@ func (a *App) reloadConfig(c *cli.Context) error {
 		return fmt.Errorf("failed to load config file: configFile=%s, err=%w", a.configFile, err)
 	}
 	// build feed generator
-	gen := generator.New(a.repository)
-	gen.Register("template", template.TemplateFeedGenerator{})
-	gen.RegisterFactory("browser", func() generator.FeedGenerator {
-		noSandbox := c.Bool("no-sandbox")
-		return browser.New(noSandbox)
-	})
+	gen := a.newFeedGenerators(c, a.repository)
 	if err := gen.LoadConfig(cnf); err != nil {
 		return err
 	}
@@ -99,6 +176,42 @@ func (a *App) reloadConfig(c *cli.Context) error {
 	return nil
 }
 
+func (a *App) newFeedGenerators(c *cli.Context, repository *repo.Repository) *generator.FeedGenerators {
+	gen := generator.New(repository)
+	gen.SetConcurrency(c.Int("max-concurrency"))
+	gen.Register("template", template.TemplateFeedGenerator{})
+	gen.RegisterFactory("browser", func() generator.FeedGenerator {
+		return browser.New(a.browserPool, c.String("base-url"))
+	})
+	gen.RegisterFactory("merge", func() generator.FeedGenerator {
+		return merge.New(gen)
+	})
+	return gen
+}
+
+func (a *App) validateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "Validate the config file and the included generator configs",
+		Action: func(c *cli.Context) error {
+			cnf, err := config.ParseConfigStrict(a.configFile)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("%s: %s", a.configFile, err), 1)
+			}
+			gen := a.newFeedGenerators(c, repo.NewMemoryRepository())
+			errs := gen.Validate(a.configFile, cnf)
+			if len(errs) > 0 {
+				for _, err := range errs {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				return cli.Exit(fmt.Sprintf("%d problem(s) found", len(errs)), 1)
+			}
+			fmt.Printf("%s: OK\n", a.configFile)
+			return nil
+		},
+	}
+}
+
 func (a *App) generateCommand() *cli.Command {
 	return &cli.Command{
 		Name: "generate",
@@ -107,7 +220,7 @@ func (a *App) generateCommand() *cli.Command {
 				Name:    "format",
 				Aliases: []string{"f"},
 				Value:   "atom",
-				Usage:   "Export format (atom/rss)",
+				Usage:   "Export format (atom/rss/html/json)",
 			},
 			&cli.StringSliceFlag{
 				Name:    "parameter",
@@ -158,16 +271,31 @@ func (a *App) generateCommand() *cli.Command {
 }
 
 func (a *App) generateFeed(feedName string, format string, parameters map[string]string, queryParameters url.Values) (*converter.Result, error) {
-	feed, err := a.feedGenerator.Generate(feedName, parameters, queryParameters)
-	if err != nil {
-		return nil, err
+	var feed *feeds.Feed
+	if s, _ := a.scheduler.Load().(*scheduler); s != nil {
+		feed = s.Get(feedName, parameters, queryParameters)
+	}
+	if feed == nil {
+		f, err := a.feedGenerator.Generate(feedName, parameters, queryParameters)
+		if err != nil {
+			return nil, err
+		}
+		feed = f
 	}
+	return convertFeed(feed, format)
+}
 
+func convertFeed(feed *feeds.Feed, format string) (*converter.Result, error) {
 	converter := converter.GetConverter(format)
 	if converter == nil {
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
-	return converter.Convert(feed)
+	result, err := converter.Convert(feed)
+	if err != nil {
+		return nil, err
+	}
+	result.LastModified = lastModified(feed)
+	return result, nil
 }
 
 func (a *App) startServerCommand() *cli.Command {
@@ -206,12 +334,18 @@ func (a *App) startServerCommand() *cli.Command {
 		l:
 			for {
 				stopCh := make(chan struct{}, 1)
-				go a.startServer(port, stopCh)
+				stoppedCh := make(chan struct{})
+				go func() {
+					a.startServer(port, stopCh)
+					close(stoppedCh)
+				}()
 				select {
 				case <-sig:
 					break l
 				case <-restartCh:
 					stopCh <- struct{}{}
+					// wait for the port to be released by the old server
+					<-stoppedCh
 				}
 			}
 			return nil
@@ -225,13 +359,36 @@ func (a *App) startServer(port int, stopChan <-chan struct{}) {
 	for name, g := range a.feedGenerator.Generators {
 		e.GET(g.Endpoint, a.generateFeedHandlerFunc(name, g))
 	}
+	a.registerIndexHandlers(e)
+	a.registerHealthHandlers(e)
+	a.registerMediaHandlers(e)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	// the scheduler of this server is stopped by this server even if a new server is started on restart
+	s := newScheduler(a.feedGenerator)
+	s.Start()
+	a.scheduler.Store(s)
 
 	log.Printf("Start server at %d", port)
-	go e.Start(fmt.Sprintf(":%d", port))
+	address := fmt.Sprintf(":%d", port)
+	// bind the port before reporting ready
+	listener, err := net.Listen("tcp", address)
+	if err == nil {
+		e.Listener = listener
+		go e.Start(address)
+		atomic.AddInt32(&a.ready, 1)
+	} else {
+		log.Printf("Failed to start server: %s", err)
+	}
 
 	<-stopChan
 	log.Println("Shutdown server")
+	if listener != nil {
+		atomic.AddInt32(&a.ready, -1)
+	}
 	e.Shutdown(context.TODO())
+	a.scheduler.CompareAndSwap(s, (*scheduler)(nil))
+	s.Stop()
 }
 
 func (a *App) generateFeedHandlerFunc(name string, g *generator.FeedGeneratorWrapper) echo.HandlerFunc {
@@ -244,12 +401,26 @@ func (a *App) generateFeedHandlerFunc(name string, g *generator.FeedGeneratorWra
 		if format == "" {
 			format = "rss"
 		}
-		result, err := a.generateFeed(name, format, parameters, c.QueryParams())
+		// 'format' is for the server, and the other unused parameters don't change the feed
+		queryParameters := g.FilterQueryParameters(c.QueryParams())
+		queryParameters.Del("format")
+		result, err := a.cache.Generate(g, format, parameters, queryParameters)
 		if err != nil {
 			c.Logger().Errorf("failed to generate: name=%s, err=%s", name, err)
-			return err
+			if g.ErrorFeed == nil {
+				return err
+			}
+			errorFeed, feedErr := a.feedGenerator.ErrorFeed(name, parameters, queryParameters, baseURL(c)+c.Request().RequestURI, err)
+			if feedErr != nil {
+				return err
+			}
+			if result, feedErr = convertFeed(errorFeed, format); feedErr != nil {
+				return err
+			}
+			// the error feed should not be cached to show the recovered feed soon
+			return writeFeedResponse(c, result, nil)
 		}
-		return c.Blob(200, result.ContentType, []byte(result.Result))
+		return writeFeedResponse(c, result, g.CacheControl)
 	}
 }
 
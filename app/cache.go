@@ -0,0 +1,111 @@
+package app
+
+import (
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/uphy/feedgen/converter"
+	"github.com/uphy/feedgen/generator"
+	"github.com/uphy/feedgen/repo"
+)
+
+type (
+	// responseCache caches the rendered feeds in the repository.
+	responseCache struct {
+		app        *App
+		refreshing map[string]struct{}
+		mutex      sync.Mutex
+	}
+)
+
+func newResponseCache(app *App) *responseCache {
+	return &responseCache{
+		app:        app,
+		refreshing: make(map[string]struct{}),
+	}
+}
+
+// Generate returns the cached feed if it is fresh enough, otherwise generates and caches the feed.
+func (c *responseCache) Generate(g *generator.FeedGeneratorWrapper, format string, parameters map[string]string, queryParameters url.Values) (*converter.Result, error) {
+	if g.Cache == nil {
+		return c.app.generateFeed(g.Name, format, parameters, queryParameters)
+	}
+
+	key := repo.GeneratedKey(g.FeedKey(parameters, queryParameters).Key(), format)
+	cached, err := c.app.repository.Response.GetResponse(key)
+	if err != nil {
+		log.Printf("Failed to get cached response: name=%s, err=%s", g.Name, err)
+		cached = nil
+	}
+	var age time.Duration
+	if cached != nil {
+		age = time.Since(cached.GeneratedAt)
+		if age < g.Cache.TTL {
+			return fromResponse(cached), nil
+		}
+		if age < g.Cache.TTL+g.Cache.StaleWhileRevalidate {
+			c.refresh(key, g, format, parameters, queryParameters)
+			return fromResponse(cached), nil
+		}
+	}
+
+	result, err := c.generate(key, g, format, parameters, queryParameters)
+	if err != nil {
+		if cached != nil && age < g.Cache.TTL+g.Cache.StaleIfError {
+			log.Printf("Serve stale response on error: name=%s, age=%s, err=%s", g.Name, age, err)
+			return fromResponse(cached), nil
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// refresh generates the feed in background unless it is being refreshed already.
+func (c *responseCache) refresh(key repo.Key, g *generator.FeedGeneratorWrapper, format string, parameters map[string]string, queryParameters url.Values) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, exist := c.refreshing[key.Key()]; exist {
+		return
+	}
+	c.refreshing[key.Key()] = struct{}{}
+	go func() {
+		defer func() {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			delete(c.refreshing, key.Key())
+		}()
+		if _, err := c.generate(key, g, format, parameters, queryParameters); err != nil {
+			log.Printf("Failed to refresh cached response: name=%s, err=%s", g.Name, err)
+		}
+	}()
+}
+
+func (c *responseCache) generate(key repo.Key, g *generator.FeedGeneratorWrapper, format string, parameters map[string]string, queryParameters url.Values) (*converter.Result, error) {
+	result, err := c.app.generateFeed(g.Name, format, parameters, queryParameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.app.repository.Response.PutResponse(key, toResponse(result)); err != nil {
+		log.Printf("Failed to cache response: name=%s, err=%s", g.Name, err)
+	}
+	return result, nil
+}
+
+func toResponse(result *converter.Result) *repo.Response {
+	return &repo.Response{
+		ContentType:  result.ContentType,
+		Body:         result.Result,
+		LastModified: result.LastModified,
+		GeneratedAt:  time.Now(),
+	}
+}
+
+func fromResponse(response *repo.Response) *converter.Result {
+	return &converter.Result{
+		ContentType:  response.ContentType,
+		Result:       response.Body,
+		LastModified: response.LastModified,
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/labstack/echo/v4"
+	"github.com/uphy/feedgen/config"
+	"github.com/uphy/feedgen/converter"
+)
+
+// writeFeedResponse writes the rendered feed with the caching headers, or 304 if the client has the same feed.
+func writeFeedResponse(c echo.Context, result *converter.Result, cacheControl *config.CacheControlConfig) error {
+	etag := result.ETag()
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	if !result.LastModified.IsZero() {
+		header.Set("Last-Modified", result.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != nil && cacheControl.MaxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheControl.MaxAge.Seconds())))
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	if notModified(c.Request(), etag, result.LastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, result.ContentType, []byte(result.Result))
+}
+
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	// If-None-Match takes precedence over If-Modified-Since (RFC 7232 section 6)
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// lastModified returns the last time the feed or its items were updated.
+func lastModified(feed *feeds.Feed) time.Time {
+	t := feed.Updated
+	if t.IsZero() {
+		t = feed.Created
+	}
+	for _, item := range feed.Items {
+		if item.Updated.After(t) {
+			t = item.Updated
+		}
+		if item.Created.After(t) {
+			t = item.Created
+		}
+	}
+	return t
+}
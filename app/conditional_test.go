@@ -0,0 +1,55 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2021, 6, 1, 10, 0, 0, 500, time.UTC)
+	etag := `"abc"`
+	tests := []struct {
+		name    string
+		headers map[string]string
+		// unknown is true if the last modified time of the feed is unknown.
+		unknown bool
+		want    bool
+	}{
+		{name: "no condition", want: false},
+		{name: "matching etag", headers: map[string]string{"If-None-Match": `"abc"`}, want: true},
+		{name: "weak etag", headers: map[string]string{"If-None-Match": `W/"abc"`}, want: true},
+		{name: "one of etags", headers: map[string]string{"If-None-Match": `"xyz", "abc"`}, want: true},
+		{name: "any etag", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "different etag", headers: map[string]string{"If-None-Match": `"xyz"`}, want: false},
+		{name: "same time", headers: map[string]string{"If-Modified-Since": "Tue, 01 Jun 2021 10:00:00 GMT"}, want: true},
+		{name: "later time", headers: map[string]string{"If-Modified-Since": "Tue, 01 Jun 2021 11:00:00 GMT"}, want: true},
+		{name: "earlier time", headers: map[string]string{"If-Modified-Since": "Tue, 01 Jun 2021 09:59:59 GMT"}, want: false},
+		{name: "invalid time", headers: map[string]string{"If-Modified-Since": "yesterday"}, want: false},
+		{name: "unknown last modified", headers: map[string]string{"If-Modified-Since": "Tue, 01 Jun 2021 11:00:00 GMT"}, unknown: true, want: false},
+		{
+			name: "etag precedes time",
+			headers: map[string]string{
+				"If-None-Match":     `"xyz"`,
+				"If-Modified-Since": "Tue, 01 Jun 2021 11:00:00 GMT",
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			modified := lastModified
+			if tt.unknown {
+				modified = time.Time{}
+			}
+			if got := notModified(req, etag, modified); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// generatorStatus is the status of a generator shown in the status page.
+	generatorStatus struct {
+		Name                string     `json:"name"`
+		Type                string     `json:"type"`
+		LastGenerated       *time.Time `json:"lastGenerated"`
+		DurationMillis      int64      `json:"durationMillis"`
+		Items               int        `json:"items"`
+		LastError           string     `json:"lastError,omitempty"`
+		LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+		ConsecutiveFailures int        `json:"consecutiveFailures"`
+	}
+)
+
+func (a *App) registerHealthHandlers(e *echo.Echo) {
+	e.GET("/healthz", a.healthzHandler)
+	e.GET("/readyz", a.readyzHandler)
+	e.GET("/status", a.statusHandler)
+}
+
+// healthzHandler reports the process is alive.
+func (a *App) healthzHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "ok")
+}
+
+// readyzHandler reports the server is ready to serve the feeds.
+func (a *App) readyzHandler(c echo.Context) error {
+	if atomic.LoadInt32(&a.ready) == 0 || a.repository == nil || a.feedGenerator == nil {
+		return c.String(http.StatusServiceUnavailable, "not ready")
+	}
+	return c.String(http.StatusOK, "ok")
+}
+
+func (a *App) statusHandler(c echo.Context) error {
+	statuses := make([]*generatorStatus, 0, len(a.feedGenerator.Generators))
+	for name, g := range a.feedGenerator.Generators {
+		status := &generatorStatus{Name: name, Type: g.Type}
+		if s := a.feedGenerator.Status(name); s != nil {
+			lastGenerated := s.LastGenerated
+			status.LastGenerated = &lastGenerated
+			status.DurationMillis = s.Duration.Milliseconds()
+			status.Items = s.Items
+			status.LastError = s.LastError
+			if !s.LastErrorAt.IsZero() {
+				lastErrorAt := s.LastErrorAt
+				status.LastErrorAt = &lastErrorAt
+			}
+			status.ConsecutiveFailures = s.ConsecutiveFailures
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return c.JSON(http.StatusOK, statuses)
+}
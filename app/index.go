@@ -0,0 +1,164 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/uphy/feedgen/generator"
+)
+
+type (
+	// indexEntry describes a configured feed in the index.
+	indexEntry struct {
+		Name            string   `json:"name"`
+		Type            string   `json:"type"`
+		Endpoint        string   `json:"endpoint"`
+		Parameters      []string `json:"parameters"`
+		QueryParameters []string `json:"queryParameters"`
+		// URLs is the URLs of the feed with the scheduled parameters, or without parameters if the endpoint has none.
+		URLs []string `json:"urls"`
+	}
+
+	opml struct {
+		XMLName xml.Name `xml:"opml"`
+		Version string   `xml:"version,attr"`
+		Head    struct {
+			Title       string `xml:"title"`
+			DateCreated string `xml:"dateCreated"`
+		} `xml:"head"`
+		Body struct {
+			Outlines []opmlOutline `xml:"outline"`
+		} `xml:"body"`
+	}
+	opmlOutline struct {
+		Type    string `xml:"type,attr"`
+		Text    string `xml:"text,attr"`
+		Title   string `xml:"title,attr"`
+		XMLURL  string `xml:"xmlUrl,attr"`
+		HTMLURL string `xml:"htmlUrl,attr,omitempty"`
+	}
+)
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>feedgen</title>
+</head>
+<body>
+<h1>feedgen</h1>
+<p><a href="index.json">JSON</a> | <a href="index.opml">OPML</a></p>
+<table>
+<tr><th>Name</th><th>Type</th><th>Endpoint</th><th>Parameters</th><th>Query parameters</th></tr>
+{{- range . }}
+<tr>
+<td>{{ .Name }}</td>
+<td>{{ .Type }}</td>
+<td>{{ if .Parameters }}{{ .Endpoint }}{{ else }}<a href="{{ .Endpoint }}">{{ .Endpoint }}</a>{{ end }}</td>
+<td>{{ range $i, $p := .Parameters }}{{ if $i }}, {{ end }}{{ $p }}{{ end }}</td>
+<td>{{ range $i, $p := .QueryParameters }}{{ if $i }}, {{ end }}{{ $p }}{{ end }}</td>
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+func (a *App) registerIndexHandlers(e *echo.Echo) {
+	e.GET("/", a.indexHandler)
+	e.GET("/index.json", a.indexJSONHandler)
+	e.GET("/index.opml", a.indexOPMLHandler)
+}
+
+func (a *App) indexHandler(c echo.Context) error {
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, a.indexEntries("")); err != nil {
+		return err
+	}
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+func (a *App) indexJSONHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, a.indexEntries(baseURL(c)))
+}
+
+func (a *App) indexOPMLHandler(c echo.Context) error {
+	o := opml{Version: "2.0"}
+	o.Head.Title = "feedgen"
+	o.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+	for _, entry := range a.indexEntries(baseURL(c)) {
+		for _, u := range entry.URLs {
+			o.Body.Outlines = append(o.Body.Outlines, opmlOutline{
+				Type:   "rss",
+				Text:   entry.Name,
+				Title:  entry.Name,
+				XMLURL: u,
+			})
+		}
+	}
+	b, err := xml.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "text/x-opml; charset=UTF-8", append([]byte(xml.Header), b...))
+}
+
+// indexEntries returns the configured feeds sorted by the name.
+func (a *App) indexEntries(base string) []*indexEntry {
+	entries := make([]*indexEntry, 0, len(a.feedGenerator.Generators))
+	for _, g := range a.feedGenerator.Generators {
+		entries = append(entries, newIndexEntry(g, base))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func newIndexEntry(g *generator.FeedGeneratorWrapper, base string) *indexEntry {
+	endpoint := "/" + strings.TrimPrefix(g.Endpoint, "/")
+	entry := &indexEntry{
+		Name:            g.Name,
+		Type:            g.Type,
+		Endpoint:        endpoint,
+		Parameters:      g.Parameters,
+		QueryParameters: g.QueryParameters,
+		URLs:            make([]string, 0),
+	}
+	if len(g.Parameters) == 0 {
+		entry.URLs = append(entry.URLs, base+endpoint)
+		return entry
+	}
+	if g.Schedule == nil {
+		return entry
+	}
+	for _, p := range g.Schedule.Parameters {
+		if len(p.Parameters) < len(g.Parameters) {
+			continue
+		}
+		u := endpoint
+		for _, name := range g.Parameters {
+			u = strings.Replace(u, ":"+name, url.PathEscape(p.Parameters[name]), 1)
+		}
+		if len(p.QueryParameters) > 0 {
+			query := make(url.Values)
+			for k, v := range p.QueryParameters {
+				query.Set(k, v)
+			}
+			u += "?" + query.Encode()
+		}
+		entry.URLs = append(entry.URLs, base+u)
+	}
+	return entry
+}
+
+func baseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/uphy/feedgen/repo"
+)
+
+func (a *App) registerMediaHandlers(e *echo.Echo) {
+	e.GET("/media/:id", a.mediaHandler)
+}
+
+// mediaHandler serves the media such as the screenshots referred from the feeds.
+func (a *App) mediaHandler(c echo.Context) error {
+	media, err := a.repository.Media.GetMedia(repo.IDKey(c.Param("id")))
+	if err != nil {
+		return err
+	}
+	if media == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+	// the ID is the hash of the content, so the content never changes while it is kept in the repository
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(repo.Retention.Seconds())))
+	return c.Blob(http.StatusOK, media.ContentType, media.Data)
+}
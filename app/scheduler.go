@@ -0,0 +1,103 @@
+package app
+
+import (
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/config"
+	"github.com/uphy/feedgen/generator"
+)
+
+type (
+	// scheduler generates the scheduled feeds in background and keeps the last successfully generated snapshots.
+	scheduler struct {
+		generators *generator.FeedGenerators
+		snapshots  map[string]*feeds.Feed
+		mutex      sync.RWMutex
+		stopCh     chan struct{}
+		stopOnce   sync.Once
+		wg         sync.WaitGroup
+	}
+)
+
+func newScheduler(generators *generator.FeedGenerators) *scheduler {
+	return &scheduler{
+		generators: generators,
+		snapshots:  make(map[string]*feeds.Feed),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (s *scheduler) Start() {
+	for name, g := range s.generators.Generators {
+		if g.Schedule == nil {
+			continue
+		}
+		s.wg.Add(1)
+		go s.run(name, g.Schedule)
+	}
+}
+
+func (s *scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+// Get returns the last generated snapshot or nil if the feed is not generated yet.
+func (s *scheduler) Get(name string, parameters map[string]string, queryParameters url.Values) *feeds.Feed {
+	wrapper, ok := s.generators.Generators[name]
+	if !ok {
+		return nil
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.snapshots[wrapper.FeedKey(parameters, queryParameters).Key()]
+}
+
+func (s *scheduler) run(name string, schedule *config.ScheduleConfig) {
+	defer s.wg.Done()
+
+	parameterSets := schedule.Parameters
+	if len(parameterSets) == 0 {
+		parameterSets = []config.ScheduleParameter{{}}
+	}
+
+	ticker := time.NewTicker(schedule.Interval)
+	defer ticker.Stop()
+	for {
+		for _, p := range parameterSets {
+			s.generate(name, p)
+		}
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) generate(name string, p config.ScheduleParameter) {
+	parameters := p.Parameters
+	if parameters == nil {
+		parameters = make(map[string]string)
+	}
+	queryParameters := make(url.Values)
+	for k, v := range p.QueryParameters {
+		queryParameters.Set(k, v)
+	}
+
+	feed, err := s.generators.Generate(name, parameters, queryParameters)
+	if err != nil {
+		log.Printf("Failed to generate scheduled feed: name=%s, parameters=%v, err=%s", name, parameters, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.snapshots[s.generators.Generators[name].FeedKey(parameters, queryParameters).Key()] = feed
+}
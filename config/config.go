@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/uphy/feedgen/template"
 	"gopkg.in/yaml.v2"
@@ -16,10 +19,68 @@ type (
 	GeneratorConfig struct {
 		Endpoint template.TemplateField
 
-		Type    string
-		Options GeneratorOptions
+		Type         string
+		Schedule     *ScheduleConfig
+		Filters      *FiltersConfig
+		CacheControl *CacheControlConfig
+		Cache        *CacheConfig
+		ErrorFeed    *ErrorFeedConfig
+		// Concurrency is the max number of the concurrent generations of the generator. 0 means unlimited.
+		Concurrency int
+		Options     GeneratorOptions
+	}
+	// GeneratorOptions is the generator type specific part of the generator config.
+	GeneratorOptions struct {
+		values map[string]interface{}
+		strict bool
+	}
+	// ScheduleConfig configures the background generation of a feed.
+	ScheduleConfig struct {
+		Interval   time.Duration       `yaml:"interval"`
+		Parameters []ScheduleParameter `yaml:"parameters"`
+	}
+	// CacheControlConfig configures the Cache-Control header of the feed responses.
+	CacheControlConfig struct {
+		MaxAge time.Duration `yaml:"maxAge"`
+	}
+	// CacheConfig configures the cache of the rendered feed responses.
+	CacheConfig struct {
+		// TTL is the duration to serve the cached response without generating.
+		TTL time.Duration `yaml:"ttl"`
+		// StaleWhileRevalidate is the duration after TTL to serve the stale response while refreshing it in background.
+		StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+		// StaleIfError is the duration after TTL to serve the stale response if the generation fails.
+		StaleIfError time.Duration `yaml:"staleIfError"`
+	}
+	// ErrorFeedConfig enables responding a feed describing the error instead of an error response when the generation fails.
+	ErrorFeedConfig struct {
+		// LastItems is the max number of the previously generated items to include in the error feed. 0 means none.
+		LastItems int `yaml:"lastItems"`
+	}
+	// FiltersConfig configures filtering and transformation of the generated items.
+	FiltersConfig struct {
+		Include  []FilterRule  `yaml:"include"`
+		Exclude  []FilterRule  `yaml:"exclude"`
+		MinAge   time.Duration `yaml:"minAge"`
+		MaxAge   time.Duration `yaml:"maxAge"`
+		Dedupe   string        `yaml:"dedupe"`
+		Sort     *SortConfig   `yaml:"sort"`
+		MaxItems int           `yaml:"maxItems"`
+	}
+	// FilterRule matches items whose field matches the regular expression.
+	FilterRule struct {
+		Field   string `yaml:"field"`
+		Pattern string `yaml:"pattern"`
+	}
+	SortConfig struct {
+		By    string `yaml:"by"`
+		Order string `yaml:"order"`
+	}
+	// ScheduleParameter is a set of parameters to generate the feed with in background.
+	ScheduleParameter struct {
+		Parameters      map[string]string `yaml:"parameters"`
+		QueryParameters map[string]string `yaml:"queryParameters"`
 	}
-	GeneratorOptions map[string]interface{}
 )
 
 func ParseConfig(file string) (*Config, error) {
@@ -35,6 +96,27 @@ func ParseConfig(file string) (*Config, error) {
 	return &c, nil
 }
 
+// ParseConfigStrict parses the config file rejecting unknown keys, including the generator options.
+func ParseConfigStrict(file string) (*Config, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var c Config
+	decoder := yaml.NewDecoder(f)
+	decoder.SetStrict(true)
+	if err := decoder.Decode(&c); err != nil {
+		return nil, err
+	}
+	for _, g := range c.Generators {
+		if g != nil {
+			g.Options.strict = true
+		}
+	}
+	return &c, nil
+}
+
 func ParseGeneratorConfig(b []byte) (*GeneratorConfig, error) {
 	var c GeneratorConfig
 	if err := yaml.Unmarshal(b, &c); err != nil {
@@ -43,12 +125,58 @@ func ParseGeneratorConfig(b []byte) (*GeneratorConfig, error) {
 	return &c, nil
 }
 
+// ParseGeneratorConfigStrict parses the generator config rejecting unknown keys.
+func ParseGeneratorConfigStrict(b []byte) (*GeneratorConfig, error) {
+	var c GeneratorConfig
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, err
+	}
+	c.Options.strict = true
+	return &c, nil
+}
+
+func NewGeneratorOptions(values map[string]interface{}) GeneratorOptions {
+	return GeneratorOptions{values: values}
+}
+
+// WithStrict returns a copy of the options which rejects unknown keys on Unmarshal if strict is true.
+func (c GeneratorOptions) WithStrict(strict bool) GeneratorOptions {
+	return GeneratorOptions{c.values, strict}
+}
+
 func (c *GeneratorOptions) Unmarshal(i interface{}) error {
-	b, err := yaml.Marshal(c)
+	b, err := yaml.Marshal(c.values)
+	if err != nil {
+		return err
+	}
+	if c.strict {
+		return withoutLineNumbers(yaml.UnmarshalStrict(b, i))
+	}
+	return withoutLineNumbers(yaml.Unmarshal(b, i))
+}
+
+var lineNumberPattern = regexp.MustCompile(`^line \d+: `)
+
+// withoutLineNumbers removes the line numbers from the yaml errors,
+// since the options are re-marshaled and the line numbers don't point to the config file.
+func withoutLineNumbers(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	errs := make([]string, len(typeErr.Errors))
+	for i, e := range typeErr.Errors {
+		errs[i] = lineNumberPattern.ReplaceAllString(e, "")
+	}
+	return &yaml.TypeError{Errors: errs}
+}
+
+func remarshal(in interface{}, out interface{}) error {
+	b, err := yaml.Marshal(in)
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(b, i)
+	return yaml.UnmarshalStrict(b, out)
 }
 
 func (c *GeneratorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -75,6 +203,75 @@ func (c *GeneratorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 		return fmt.Errorf("'endpoint' is required")
 	}
 
-	c.Options = m
+	if s, exist := m["schedule"]; exist {
+		var schedule ScheduleConfig
+		if err := remarshal(s, &schedule); err != nil {
+			return fmt.Errorf("failed to parse 'schedule': %w", err)
+		}
+		if schedule.Interval <= 0 {
+			return fmt.Errorf("'schedule.interval' must be a positive duration: %v", s)
+		}
+		c.Schedule = &schedule
+		delete(m, "schedule")
+	}
+
+	if f, exist := m["filters"]; exist {
+		var filters FiltersConfig
+		if err := remarshal(f, &filters); err != nil {
+			return fmt.Errorf("failed to parse 'filters': %w", err)
+		}
+		c.Filters = &filters
+		delete(m, "filters")
+	}
+
+	if cc, exist := m["cacheControl"]; exist {
+		var cacheControl CacheControlConfig
+		if err := remarshal(cc, &cacheControl); err != nil {
+			return fmt.Errorf("failed to parse 'cacheControl': %w", err)
+		}
+		c.CacheControl = &cacheControl
+		delete(m, "cacheControl")
+	}
+
+	if cc, exist := m["cache"]; exist {
+		var cache CacheConfig
+		if err := remarshal(cc, &cache); err != nil {
+			return fmt.Errorf("failed to parse 'cache': %w", err)
+		}
+		if cache.TTL <= 0 {
+			return fmt.Errorf("'cache.ttl' must be a positive duration: %v", cc)
+		}
+		c.Cache = &cache
+		delete(m, "cache")
+	}
+
+	if e, exist := m["errorFeed"]; exist {
+		if enabled, ok := e.(bool); ok {
+			if enabled {
+				c.ErrorFeed = &ErrorFeedConfig{}
+			}
+		} else {
+			var errorFeed ErrorFeedConfig
+			if err := remarshal(e, &errorFeed); err != nil {
+				return fmt.Errorf("failed to parse 'errorFeed': %w", err)
+			}
+			if errorFeed.LastItems < 0 {
+				return fmt.Errorf("'errorFeed.lastItems' must be a non-negative integer: %v", e)
+			}
+			c.ErrorFeed = &errorFeed
+		}
+		delete(m, "errorFeed")
+	}
+
+	if cc, exist := m["concurrency"]; exist {
+		if concurrency, ok := cc.(int); ok && concurrency >= 0 {
+			c.Concurrency = concurrency
+		} else {
+			return fmt.Errorf("'concurrency' must be a non-negative integer: %v", cc)
+		}
+		delete(m, "concurrency")
+	}
+
+	c.Options = NewGeneratorOptions(m)
 	return nil
 }
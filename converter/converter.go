@@ -1,6 +1,12 @@
 package converter
 
-import "github.com/gorilla/feeds"
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
 
 type (
 	Converter interface {
@@ -9,6 +15,8 @@ type (
 	Result struct {
 		ContentType string
 		Result      string
+		// LastModified is the last time the feed or its items were updated.
+		LastModified time.Time
 	}
 )
 
@@ -20,10 +28,18 @@ func GetConverter(name string) Converter {
 		return &atomConverter{}
 	case "html":
 		return &htmlConverter{}
+	case "json":
+		return &jsonConverter{}
 	}
 	return nil
 }
 
 func newResult(contentType, result string) *Result {
-	return &Result{contentType, result}
+	return &Result{ContentType: contentType, Result: result}
+}
+
+// ETag returns a strong entity tag of the rendered feed.
+func (r *Result) ETag() string {
+	b := sha256.Sum256([]byte(r.ContentType + "\n" + r.Result))
+	return fmt.Sprintf(`"%x"`, b[:16])
 }
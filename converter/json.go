@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type (
+	jsonConverter struct {
+	}
+	jsonFeed struct {
+		Version     string          `json:"version"`
+		Title       string          `json:"title"`
+		HomePageURL string          `json:"home_page_url,omitempty"`
+		Description string          `json:"description,omitempty"`
+		Icon        string          `json:"icon,omitempty"`
+		Authors     []*jsonAuthor   `json:"authors,omitempty"`
+		Items       []*jsonFeedItem `json:"items"`
+	}
+	jsonAuthor struct {
+		Name string `json:"name,omitempty"`
+		URL  string `json:"url,omitempty"`
+	}
+	jsonFeedItem struct {
+		ID            string            `json:"id"`
+		URL           string            `json:"url,omitempty"`
+		ExternalURL   string            `json:"external_url,omitempty"`
+		Title         string            `json:"title,omitempty"`
+		ContentHTML   *string           `json:"content_html,omitempty"`
+		ContentText   *string           `json:"content_text,omitempty"`
+		Summary       string            `json:"summary,omitempty"`
+		Image         string            `json:"image,omitempty"`
+		DatePublished string            `json:"date_published,omitempty"`
+		DateModified  string            `json:"date_modified,omitempty"`
+		Authors       []*jsonAuthor     `json:"authors,omitempty"`
+		Attachments   []*jsonAttachment `json:"attachments,omitempty"`
+	}
+	jsonAttachment struct {
+		URL         string `json:"url"`
+		MimeType    string `json:"mime_type"`
+		SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+	}
+)
+
+func (c *jsonConverter) Convert(feed *feeds.Feed) (*Result, error) {
+	f := &jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       feed.Title,
+		Description: feed.Description,
+		Authors:     toJSONAuthors(feed.Author),
+		Items:       make([]*jsonFeedItem, 0, len(feed.Items)),
+	}
+	if feed.Link != nil {
+		f.HomePageURL = feed.Link.Href
+	}
+	if feed.Image != nil {
+		f.Icon = feed.Image.Url
+	}
+	for _, item := range feed.Items {
+		f.Items = append(f.Items, toJSONFeedItem(item))
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return newResult("application/feed+json", string(b)), nil
+}
+
+func toJSONFeedItem(item *feeds.Item) *jsonFeedItem {
+	i := &jsonFeedItem{
+		ID:            item.Id,
+		Title:         item.Title,
+		DatePublished: formatJSONFeedTime(item.Created),
+		DateModified:  formatJSONFeedTime(item.Updated),
+		Authors:       toJSONAuthors(item.Author),
+	}
+	if item.Link != nil {
+		i.URL = item.Link.Href
+	}
+	if item.Source != nil {
+		i.ExternalURL = item.Source.Href
+	}
+	if len(i.ID) == 0 {
+		i.ID = i.URL
+	}
+	// JSON Feed requires either content_html or content_text, even if empty.
+	if len(item.Content) > 0 {
+		content := item.Content
+		i.ContentHTML = &content
+		i.Summary = item.Description
+	} else {
+		description := item.Description
+		i.ContentText = &description
+	}
+	if item.Enclosure != nil && len(item.Enclosure.Url) > 0 {
+		attachment := &jsonAttachment{
+			URL:      item.Enclosure.Url,
+			MimeType: item.Enclosure.Type,
+		}
+		if len(attachment.MimeType) == 0 {
+			attachment.MimeType = "application/octet-stream"
+		}
+		if size, err := strconv.ParseInt(item.Enclosure.Length, 10, 64); err == nil {
+			attachment.SizeInBytes = size
+		}
+		i.Attachments = []*jsonAttachment{attachment}
+	}
+	return i
+}
+
+func toJSONAuthors(author *feeds.Author) []*jsonAuthor {
+	if author == nil || len(author.Name) == 0 {
+		return nil
+	}
+	a := &jsonAuthor{Name: author.Name}
+	if len(author.Email) > 0 {
+		a.URL = "mailto:" + author.Email
+	}
+	return []*jsonAuthor{a}
+}
+
+func formatJSONFeedTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,295 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/template"
+)
+
+type (
+	ActionConfig struct {
+		Navigate        *template.TemplateField `yaml:"navigate"`
+		WaitVisible     *template.TemplateField `yaml:"waitVisible"`
+		WaitNotVisible  *template.TemplateField `yaml:"waitNotVisible"`
+		WaitNetworkIdle *time.Duration          `yaml:"waitNetworkIdle"`
+		Click           *template.TemplateField `yaml:"click"`
+		Type            *TypeActionConfig       `yaml:"type"`
+		Press           *template.TemplateField `yaml:"press"`
+		Select          *SelectActionConfig     `yaml:"select"`
+		ScrollToBottom  *ScrollActionConfig     `yaml:"scrollToBottom"`
+		Eval            *template.TemplateField `yaml:"eval"`
+		Feed            *template.TemplateField `yaml:"feed"`
+		Items           *template.TemplateField `yaml:"items"`
+		Sleep           *time.Duration          `yaml:"sleep"`
+		Screenshot      *ScreenshotConfig       `yaml:"screenshot"`
+		// Timeout is the timeout of the action.
+		Timeout *time.Duration `yaml:"timeout"`
+		// Optional ignores the failure of the action.
+		Optional bool `yaml:"optional"`
+	}
+	// TypeActionConfig types the text into the element.
+	TypeActionConfig struct {
+		Selector template.TemplateField `yaml:"selector"`
+		Text     template.TemplateField `yaml:"text"`
+	}
+	// SelectActionConfig selects the option of the select element by the value.
+	SelectActionConfig struct {
+		Selector template.TemplateField `yaml:"selector"`
+		Value    template.TemplateField `yaml:"value"`
+	}
+	// ScrollActionConfig scrolls to the bottom of the page repeatedly to load more contents.
+	// `scrollToBottom: 3` scrolls 3 times.
+	ScrollActionConfig struct {
+		// Times is the number of the scrolls. Defaults to 1, or 20 if UntilNoNewContent is set.
+		Times int `yaml:"times"`
+		// UntilNoNewContent stops scrolling when the page height doesn't change.
+		UntilNoNewContent bool `yaml:"untilNoNewContent"`
+		// Wait is the duration to wait for the contents loaded after a scroll. Defaults to 1s.
+		Wait time.Duration `yaml:"wait"`
+	}
+	scrollActionConfigYAML struct {
+		Times             int           `yaml:"times"`
+		UntilNoNewContent bool          `yaml:"untilNoNewContent"`
+		Wait              time.Duration `yaml:"wait"`
+	}
+)
+
+const (
+	defaultScrollTimes           = 1
+	defaultScrollTimesUntilNoNew = 20
+	defaultScrollWait            = time.Second
+)
+
+// keys is the names of the special keys for the 'press' action.
+var keys = map[string]string{
+	"Backspace": kb.Backspace,
+	"Tab":       kb.Tab,
+	"Enter":     kb.Enter,
+	"Escape":    kb.Escape,
+	"Delete":    kb.Delete,
+	"ArrowDown": kb.ArrowDown,
+	"ArrowUp":   kb.ArrowUp,
+	"End":       kb.End,
+	"Home":      kb.Home,
+	"PageDown":  kb.PageDown,
+	"PageUp":    kb.PageUp,
+}
+
+func (c *ScrollActionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var times int
+	if err := unmarshal(&times); err == nil {
+		c.Times = times
+		return nil
+	}
+	var y scrollActionConfigYAML
+	if err := unmarshal(&y); err != nil {
+		return err
+	}
+	c.Times = y.Times
+	c.UntilNoNewContent = y.UntilNoNewContent
+	c.Wait = y.Wait
+	return nil
+}
+
+// name returns the name of the action, or empty string if the action is not exactly one.
+func (c *ActionConfig) name() string {
+	name := ""
+	for n, defined := range map[string]bool{
+		"navigate":        c.Navigate != nil,
+		"waitVisible":     c.WaitVisible != nil,
+		"waitNotVisible":  c.WaitNotVisible != nil,
+		"waitNetworkIdle": c.WaitNetworkIdle != nil,
+		"click":           c.Click != nil,
+		"type":            c.Type != nil,
+		"press":           c.Press != nil,
+		"select":          c.Select != nil,
+		"scrollToBottom":  c.ScrollToBottom != nil,
+		"eval":            c.Eval != nil,
+		"feed":            c.Feed != nil,
+		"items":           c.Items != nil,
+		"sleep":           c.Sleep != nil,
+		"screenshot":      c.Screenshot != nil,
+	} {
+		if !defined {
+			continue
+		}
+		if name != "" {
+			return ""
+		}
+		name = n
+	}
+	return name
+}
+
+// build builds the chromedp action evaluating the templates.
+// feed is updated by 'feed', 'items' and 'screenshot' actions.
+func (c *ActionConfig) build(templateContext *template.TemplateContext, feed **feeds.Feed, store *mediaStore) (chromedp.Action, error) {
+	var action chromedp.Action
+	switch {
+	case c.Navigate != nil:
+		action = chromedp.Navigate(c.Navigate.MustEvaluate(templateContext))
+	case c.WaitVisible != nil:
+		action = chromedp.WaitVisible(c.WaitVisible.MustEvaluate(templateContext), chromedp.ByQuery)
+	case c.WaitNotVisible != nil:
+		action = chromedp.WaitNotVisible(c.WaitNotVisible.MustEvaluate(templateContext), chromedp.ByQuery)
+	case c.WaitNetworkIdle != nil:
+		action = waitNetworkIdle(*c.WaitNetworkIdle)
+	case c.Click != nil:
+		action = chromedp.Click(c.Click.MustEvaluate(templateContext), chromedp.ByQuery)
+	case c.Type != nil:
+		action = chromedp.SendKeys(c.Type.Selector.MustEvaluate(templateContext), c.Type.Text.MustEvaluate(templateContext), chromedp.ByQuery)
+	case c.Press != nil:
+		key := c.Press.MustEvaluate(templateContext)
+		if k, exist := keys[key]; exist {
+			key = k
+		}
+		action = chromedp.KeyEvent(key)
+	case c.Select != nil:
+		action = selectValue(c.Select.Selector.MustEvaluate(templateContext), c.Select.Value.MustEvaluate(templateContext))
+	case c.ScrollToBottom != nil:
+		action = scrollToBottom(c.ScrollToBottom)
+	case c.Eval != nil:
+		action = chromedp.Evaluate(c.Eval.MustEvaluate(templateContext), nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		})
+	case c.Feed != nil:
+		action = chromedp.Evaluate(c.Feed.MustEvaluate(templateContext), feed)
+	case c.Items != nil:
+		action = chromedp.Evaluate(c.Items.MustEvaluate(templateContext), &(*feed).Items)
+	case c.Sleep != nil:
+		action = chromedp.Sleep(*c.Sleep)
+	case c.Screenshot != nil:
+		action = c.Screenshot.screenshot(templateContext, store, func(url string) {
+			(*feed).Image = &feeds.Image{Url: url}
+		})
+	default:
+		return nil, errors.New("exactly one action is required")
+	}
+
+	if c.Timeout == nil && !c.Optional {
+		return action, nil
+	}
+	// copy the fields not to refer to the config on running, which may be a reused loop variable
+	name := c.name()
+	timeout := c.Timeout
+	optional := c.Optional
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if timeout != nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+			defer cancel()
+		}
+		err := action.Do(ctx)
+		if err != nil && optional {
+			log.Printf("Ignore the failure of optional action: action=%s, err=%s", name, err)
+			return nil
+		}
+		return err
+	}), nil
+}
+
+func selectValue(selector string, value string) chromedp.Action {
+	s, _ := json.Marshal(selector)
+	v, _ := json.Marshal(value)
+	script := fmt.Sprintf(`(function(){
+	const e = document.querySelector(%s);
+	e.value = %s;
+	e.dispatchEvent(new Event('input', {bubbles: true}));
+	e.dispatchEvent(new Event('change', {bubbles: true}));
+	return true;
+}())`, s, v)
+	return chromedp.Tasks{
+		chromedp.WaitReady(selector, chromedp.ByQuery),
+		chromedp.Evaluate(script, nil),
+	}
+}
+
+func scrollToBottom(c *ScrollActionConfig) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		times := c.Times
+		if times <= 0 {
+			times = defaultScrollTimes
+			if c.UntilNoNewContent {
+				times = defaultScrollTimesUntilNoNew
+			}
+		}
+		wait := c.Wait
+		if wait <= 0 {
+			wait = defaultScrollWait
+		}
+		var height int64
+		if err := chromedp.Evaluate(`document.body.scrollHeight`, &height).Do(ctx); err != nil {
+			return err
+		}
+		for i := 0; i < times; i++ {
+			if err := chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil).Do(ctx); err != nil {
+				return err
+			}
+			if err := chromedp.Sleep(wait).Do(ctx); err != nil {
+				return err
+			}
+			var newHeight int64
+			if err := chromedp.Evaluate(`document.body.scrollHeight`, &newHeight).Do(ctx); err != nil {
+				return err
+			}
+			if c.UntilNoNewContent && newHeight <= height {
+				break
+			}
+			height = newHeight
+		}
+		return nil
+	})
+}
+
+// waitNetworkIdle waits until no network request is in flight for the idle duration.
+func waitNetworkIdle(idle time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var mutex sync.Mutex
+		inflight := make(map[network.RequestID]struct{})
+		lastActivity := time.Now()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch e := ev.(type) {
+			case *network.EventRequestWillBeSent:
+				inflight[e.RequestID] = struct{}{}
+			case *network.EventLoadingFinished:
+				delete(inflight, e.RequestID)
+			case *network.EventLoadingFailed:
+				delete(inflight, e.RequestID)
+			default:
+				return
+			}
+			lastActivity = time.Now()
+		})
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				mutex.Lock()
+				idled := len(inflight) == 0 && time.Since(lastActivity) >= idle
+				mutex.Unlock()
+				if idled {
+					return nil
+				}
+			}
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/gorilla/feeds"
 	"github.com/uphy/feedgen/config"
 	"github.com/uphy/feedgen/generator"
+	feedtemplate "github.com/uphy/feedgen/generator/template"
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/template"
 )
 
@@ -20,22 +22,27 @@ type (
 		Browser struct {
 			Visible bool           `yaml:"visible"`
 			Timeout *time.Duration `yaml:"timeout"`
+			// RemoteURL is the DevTools URL of the remote browser to use instead of the shared one.
+			RemoteURL string `yaml:"remoteURL"`
+			// ScreenshotFormat is the image format of the 'Screenshot' template func, png or webp.
+			ScreenshotFormat string `yaml:"screenshotFormat"`
 		} `yaml:"browser"`
+		// Feed, List, Item and Limit extract the feed from the DOM rendered after the actions, same as the template generator.
+		Feed  feedtemplate.FeedConfig `yaml:"feed"`
+		List  template.TemplateField  `yaml:"list"`
+		Item  feedtemplate.ItemConfig `yaml:"item"`
+		Limit int                     `yaml:"limit"`
 	}
 	BrowserFeedGenerator struct {
-		noSandbox bool
-		config    *BrowserFeedGeneratorConfig
-	}
-	ActionConfig struct {
-		WaitVisible *template.TemplateField `yaml:"waitVisible"`
-		Feed        *template.TemplateField `yaml:"feed"`
-		Items       *template.TemplateField `yaml:"items"`
-		Sleep       *time.Duration          `yaml:"sleep"`
+		pool *Pool
+		// baseURL is the URL of the server to build the URLs of the screenshots.
+		baseURL string
+		config  *BrowserFeedGeneratorConfig
 	}
 )
 
-func New(noSandbox bool) *BrowserFeedGenerator {
-	return &BrowserFeedGenerator{noSandbox: noSandbox}
+func New(pool *Pool, baseURL string) *BrowserFeedGenerator {
+	return &BrowserFeedGenerator{pool: pool, baseURL: baseURL}
 }
 
 func (g *BrowserFeedGenerator) LoadOptions(options config.GeneratorOptions) error {
@@ -43,16 +50,36 @@ func (g *BrowserFeedGenerator) LoadOptions(options config.GeneratorOptions) erro
 	if err := options.Unmarshal(&config); err != nil {
 		return err
 	}
+	for i, action := range config.Actions {
+		if action.name() == "" {
+			return fmt.Errorf("'actions[%d]' must have exactly one action", i)
+		}
+		if action.Screenshot != nil {
+			if err := validateScreenshotFormat(action.Screenshot.Format); err != nil {
+				return fmt.Errorf("invalid 'actions[%d].screenshot': %w", i, err)
+			}
+		}
+	}
+	if err := validateScreenshotFormat(config.Browser.ScreenshotFormat); err != nil {
+		return fmt.Errorf("invalid 'browser.screenshotFormat': %w", err)
+	}
 	g.config = config
 	return nil
 }
 
+func (g *BrowserFeedGenerator) ValidationTarget() (interface{}, []string) {
+	return g.config, append(feedtemplate.FuncNames(), "Screenshot")
+}
+
 func (g *BrowserFeedGenerator) Generate(generatorContext *generator.Context) (*feeds.Feed, error) {
 	templateContext := generatorContext.TemplateContext
 	url := g.config.URL.MustEvaluate(templateContext)
 
 	// Start Chrome
-	ctx, cancel := g.buildChromeContext()
+	ctx, cancel, err := g.buildChromeContext()
+	if err != nil {
+		return nil, err
+	}
 	defer cancel()
 
 	feed := new(feeds.Feed)
@@ -62,49 +89,118 @@ func (g *BrowserFeedGenerator) Generate(generatorContext *generator.Context) (*f
 
 	// Build actions
 	actions := make([]chromedp.Action, 0)
-	actions = append(actions, chromedp.Navigate(url))
-	for _, command := range g.config.Actions {
-		if command.WaitVisible != nil {
-			query := command.WaitVisible.MustEvaluate(templateContext)
-			actions = append(actions, chromedp.WaitVisible(query, chromedp.ByQuery))
-		} else if command.Feed != nil {
-			script := command.Feed.MustEvaluate(templateContext)
-			actions = append(actions, chromedp.Evaluate(script, &feed))
-		} else if command.Items != nil {
-			script := command.Items.MustEvaluate(templateContext)
-			actions = append(actions, chromedp.Evaluate(script, &feed.Items))
-		} else if command.Sleep != nil {
-			actions = append(actions, chromedp.Sleep(*command.Sleep))
+	labels := generatorContext.Labels
+	store := &mediaStore{repository: generatorContext.Repository, baseURL: g.baseURL}
+	actions = append(actions, observeAction(labels, "navigate", chromedp.Navigate(url)))
+	for i, command := range g.config.Actions {
+		action, err := command.build(templateContext, &feed, store)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'actions[%d]': %w", i, err)
+		}
+		actions = append(actions, observeAction(labels, command.name(), action))
+	}
+	// Capture the rendered DOM
+	var location, html string
+	if g.config.List.IsDefined() {
+		capture := chromedp.Tasks{
+			chromedp.Location(&location),
+			chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+		}
+		if usesScreenshot(g.config) {
+			capture = append(chromedp.Tasks{tagElements()}, capture...)
 		}
+		actions = append(actions, observeAction(labels, "capture", capture))
 	}
 	// Run actions
-	err := chromedp.Run(ctx, actions...)
-	if err != nil {
+	if err := chromedp.Run(ctx, actions...); err != nil {
 		return nil, fmt.Errorf("failed on Chrome action: %w", err)
 	}
-	return feed, nil
+	if feed.Image != nil && feed.Image.Title == "" {
+		feed.Image.Title = feed.Title
+		if feed.Link != nil {
+			feed.Image.Link = feed.Link.Href
+		}
+	}
+	if !g.config.List.IsDefined() {
+		return feed, nil
+	}
+
+	// Extract the feed from the DOM, capturing the screenshots of the items in the tab still open
+	doc, err := feedtemplate.ParseHTML(html)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract feed from the rendered page: %w", err)
+	}
+	screenshots := newElementScreenshots(ctx, store, g.config.Browser.ScreenshotFormat)
+	screenshots.untag(doc.Node())
+	templateContext.AddFuncs(map[string]interface{}{
+		"Screenshot": screenshots.screenshot,
+	})
+	rendered, err := feedtemplate.GenerateFromHTML(generatorContext, &feedtemplate.TemplateFeedGeneratorConfig{
+		Feed:  g.config.Feed,
+		List:  g.config.List,
+		Item:  g.config.Item,
+		Limit: g.config.Limit,
+	}, location, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract feed from the rendered page: %w", err)
+	}
+	if rendered.Link == nil {
+		rendered.Link = feed.Link
+	}
+	if rendered.Image == nil {
+		rendered.Image = feed.Image
+	}
+	store.refresh(rendered)
+	return rendered, nil
 }
 
-func (g *BrowserFeedGenerator) buildChromeContext() (context.Context, func()) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", !g.config.Browser.Visible),
-		chromedp.Flag("window-size", "1920,1080"),
-	)
-	if g.noSandbox {
-		// for execution on heroku
-		opts = append(opts,
-			chromedp.Flag("no-sandbox", "true"),
-			chromedp.Flag("disable-setuid-sandbox", "true"),
-		)
-	}
-	allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, cancel := chromedp.NewContext(
-		allocCtx,
-		chromedp.WithLogf(log.Printf),
-	)
+// observeAction wraps the action to observe its duration.
+func observeAction(labels metrics.Labels, name string, action chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		start := time.Now()
+		defer func() {
+			metrics.ObserveBrowserAction(labels, name, time.Since(start))
+		}()
+		return action.Do(ctx)
+	})
+}
+
+// buildChromeContext opens a tab in the shared pool or the remote browser, or starts a dedicated Chrome if the browser should be visible.
+func (g *BrowserFeedGenerator) buildChromeContext() (context.Context, func(), error) {
+	ctx, timeoutCancel := context.Background(), context.CancelFunc(func() {})
 	if g.config.Browser.Timeout != nil {
-		ctx, cancel = context.WithTimeout(ctx, *g.config.Browser.Timeout)
+		ctx, timeoutCancel = context.WithTimeout(ctx, *g.config.Browser.Timeout)
+	}
 
+	pool := g.pool
+	if g.config.Browser.RemoteURL != "" {
+		pool = pool.Remote(g.config.Browser.RemoteURL)
+	} else if g.config.Browser.Visible && g.pool.options.RemoteURL == "" {
+		allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, allocatorOptions(g.pool.options.NoSandbox, true)...)
+		browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+		return browserCtx, func() {
+			browserCancel()
+			allocCancel()
+			timeoutCancel()
+		}, nil
+	}
+
+	tabCtx, tabCancel, err := pool.NewTab(ctx)
+	if err != nil {
+		timeoutCancel()
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		tabCtx, cancel = context.WithDeadline(tabCtx, deadline)
+		return tabCtx, func() {
+			cancel()
+			tabCancel()
+			timeoutCancel()
+		}, nil
 	}
-	return ctx, cancel
+	return tabCtx, func() {
+		tabCancel()
+		timeoutCancel()
+	}, nil
 }
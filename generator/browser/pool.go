@@ -0,0 +1,300 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+type (
+	// Pool shares a Chrome process across the browser generators.
+	// Chrome is started on demand, and shut down when no tab is used for the idle timeout.
+	Pool struct {
+		options   PoolOptions
+		semaphore chan struct{}
+
+		mutex sync.Mutex
+		// browser is the running or starting Chrome, nil if not started.
+		browser *browserInstance
+		// inUse is the number of the tabs open or opening.
+		inUse     int
+		idleTimer *time.Timer
+		// remotes is the pools of the remote browsers specified by the generators.
+		remotes map[string]*Pool
+	}
+	// browserInstance is a Chrome started once by the first tab, outside the lock of the pool not to block the others.
+	browserInstance struct {
+		once   sync.Once
+		ctx    context.Context
+		cancel context.CancelFunc
+		err    error
+	}
+	PoolOptions struct {
+		// NoSandbox disables the sandbox of Chrome, required to run as root such as in containers.
+		NoSandbox bool
+		// Size is the max number of the tabs open at the same time. The other generations wait for a tab.
+		Size int
+		// IdleTimeout is the duration to keep Chrome running without any tab. 0 means to keep it forever.
+		// It is ignored for the remote browser, which is kept connected not to close it.
+		IdleTimeout time.Duration
+		// RemoteURL is the DevTools URL of the remote browser to use instead of starting Chrome.
+		// ws:// URL is used as is, and http:// URL is resolved to the websocket URL by /json/version.
+		RemoteURL string
+	}
+)
+
+const defaultPoolSize = 2
+
+func NewPool(options PoolOptions) *Pool {
+	if options.Size <= 0 {
+		options.Size = defaultPoolSize
+	}
+	if options.RemoteURL != "" {
+		options.IdleTimeout = 0
+	}
+	return &Pool{
+		options:   options,
+		semaphore: make(chan struct{}, options.Size),
+		remotes:   make(map[string]*Pool),
+	}
+}
+
+// Remote returns the pool of the remote browser at the URL with the same options.
+func (p *Pool) Remote(url string) *Pool {
+	if url == p.options.RemoteURL {
+		return p
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	remote, exist := p.remotes[url]
+	if !exist {
+		options := p.options
+		options.RemoteURL = url
+		remote = NewPool(options)
+		p.remotes[url] = remote
+	}
+	return remote
+}
+
+// NewTab opens a tab waiting for the pool to have a room, and returns the context of the tab and the func to close it.
+func (p *Pool) NewTab(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	select {
+	case p.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("failed to wait for a browser tab: %w", ctx.Err())
+	}
+
+	tabCtx, tabCancel, err := p.newTab()
+	if err != nil {
+		<-p.semaphore
+		return nil, nil, err
+	}
+	var once sync.Once
+	return tabCtx, func() {
+		once.Do(func() {
+			tabCancel()
+			p.release()
+			<-p.semaphore
+		})
+	}, nil
+}
+
+func (p *Pool) newTab() (context.Context, context.CancelFunc, error) {
+	// retry once with a new Chrome in case the running one is crashed
+	var lastErr error
+	for i := 0; i < 2; i++ {
+		b := p.acquire()
+		b.once.Do(func() {
+			b.ctx, b.cancel, b.err = p.start()
+		})
+		if b.err != nil {
+			p.discard(b, true)
+			return nil, nil, b.err
+		}
+		tabCtx, tabCancel := chromedp.NewContext(b.ctx)
+		err := chromedp.Run(tabCtx)
+		if err == nil {
+			return tabCtx, tabCancel, nil
+		}
+		tabCancel()
+		// don't restart the browser used by other tabs
+		if !p.discard(b, b.ctx.Err() != nil) {
+			return nil, nil, fmt.Errorf("failed to open a browser tab: %w", err)
+		}
+		log.Printf("Failed to open a browser tab, restart the browser: err=%s", err)
+		lastErr = err
+	}
+	return nil, nil, fmt.Errorf("failed to open a browser tab: %w", lastErr)
+}
+
+// acquire returns the browser to open a tab, counting the tab in use.
+func (p *Pool) acquire() *browserInstance {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.idleTimer != nil {
+		p.idleTimer.Stop()
+		p.idleTimer = nil
+	}
+	if p.browser == nil {
+		p.browser = &browserInstance{}
+	}
+	p.inUse++
+	return p.browser
+}
+
+// discard uncounts the tab failed to open, and stops the browser if it is broken or not used by the other tabs.
+// It returns true if the browser is stopped.
+func (p *Pool) discard(b *browserInstance, broken bool) bool {
+	p.mutex.Lock()
+	p.inUse--
+	if p.browser != b || (!broken && p.inUse > 0) {
+		p.mutex.Unlock()
+		return p.browser != b
+	}
+	p.browser = nil
+	p.mutex.Unlock()
+	b.stop()
+	return true
+}
+
+func (p *Pool) release() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.inUse--
+	if p.inUse > 0 || p.options.IdleTimeout <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(p.options.IdleTimeout, func() {
+		p.mutex.Lock()
+		b := p.browser
+		if p.idleTimer != timer || p.inUse > 0 || b == nil {
+			p.mutex.Unlock()
+			return
+		}
+		p.browser = nil
+		p.idleTimer = nil
+		p.mutex.Unlock()
+		log.Println("Shutdown idle browser")
+		b.stop()
+	})
+	p.idleTimer = timer
+}
+
+// start starts Chrome. It is called without the lock, since it may take a while.
+func (p *Pool) start() (context.Context, context.CancelFunc, error) {
+	var allocCtx context.Context
+	var allocCancel context.CancelFunc
+	if p.options.RemoteURL != "" {
+		wsURL, err := resolveWebSocketURL(p.options.RemoteURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve remote browser URL: %w", err)
+		}
+		allocCtx, allocCancel = chromedp.NewRemoteAllocator(context.Background(), wsURL)
+	} else {
+		allocCtx, allocCancel = chromedp.NewExecAllocator(context.Background(), allocatorOptions(p.options.NoSandbox, false)...)
+	}
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+	return browserCtx, func() {
+		browserCancel()
+		allocCancel()
+	}, nil
+}
+
+// stop stops Chrome, waiting for it if it is starting.
+func (b *browserInstance) stop() {
+	b.once.Do(func() {
+		b.err = errors.New("browser stopped")
+	})
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// Close stops Chrome, and disconnects the remote browsers.
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	remotes := make([]*Pool, 0, len(p.remotes))
+	for _, remote := range p.remotes {
+		remotes = append(remotes, remote)
+	}
+	if p.idleTimer != nil {
+		p.idleTimer.Stop()
+		p.idleTimer = nil
+	}
+	b := p.browser
+	p.browser = nil
+	p.mutex.Unlock()
+
+	for _, remote := range remotes {
+		remote.Close()
+	}
+	if b != nil {
+		b.stop()
+	}
+}
+
+// resolveWebSocketURL returns the websocket URL of the browser from the DevTools URL.
+func resolveWebSocketURL(devtoolsURL string) (string, error) {
+	u, err := url.Parse(devtoolsURL)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		return devtoolsURL, nil
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/json/version"
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: url=%s, status=%s", u, resp.Status)
+	}
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", err
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return "", fmt.Errorf("webSocketDebuggerUrl not found: url=%s", u)
+	}
+	return version.WebSocketDebuggerURL, nil
+}
+
+func allocatorOptions(noSandbox bool, visible bool) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", !visible),
+		chromedp.Flag("window-size", "1920,1080"),
+	)
+	if noSandbox {
+		// for execution on heroku
+		opts = append(opts,
+			chromedp.Flag("no-sandbox", "true"),
+			chromedp.Flag("disable-setuid-sandbox", "true"),
+		)
+	}
+	return opts
+}
@@ -0,0 +1,277 @@
+package browser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/gorilla/feeds"
+	feedtemplate "github.com/uphy/feedgen/generator/template"
+	"github.com/uphy/feedgen/repo"
+	"github.com/uphy/feedgen/template"
+	"golang.org/x/net/html"
+)
+
+type (
+	// ScreenshotConfig captures the full page, or the element if the selector is specified.
+	// `screenshot: '#main'` captures the element.
+	ScreenshotConfig struct {
+		Selector template.TemplateField `yaml:"selector"`
+		// Format is the image format, png or webp. Defaults to png.
+		Format string `yaml:"format"`
+	}
+	screenshotConfigYAML struct {
+		Selector template.TemplateField `yaml:"selector"`
+		Format   string                 `yaml:"format"`
+	}
+	// mediaStore stores the captured images in the repository, and returns the URLs served by the server.
+	mediaStore struct {
+		repository *repo.Repository
+		baseURL    string
+	}
+	// elementScreenshots captures the elements of the rendered document for the 'Screenshot' template func.
+	elementScreenshots struct {
+		ctx    context.Context
+		store  *mediaStore
+		format string
+		// tags is the tags of the elements in the captured document, removed from the document not to be published.
+		tags map[*html.Node]string
+		// cache is the URLs of the captured screenshots by the tag of the element, or empty string for the full page.
+		cache map[string]string
+		mutex sync.Mutex
+	}
+)
+
+const (
+	defaultScreenshotFormat = "png"
+	// elementTagAttribute is the attribute to tag the elements for the 'Screenshot' template func.
+	elementTagAttribute = "data-feedgen-id"
+)
+
+// mediaURLPattern matches the URLs of the media stored by mediaStore.
+var mediaURLPattern = regexp.MustCompile(`/media/([0-9a-f]{32}\.(?:png|webp))`)
+
+func (c *ScreenshotConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var selector string
+	if err := unmarshal(&selector); err == nil {
+		c.Selector = template.NewTemplateField(selector)
+		return nil
+	}
+	var y screenshotConfigYAML
+	if err := unmarshal(&y); err != nil {
+		return err
+	}
+	c.Selector = y.Selector
+	c.Format = y.Format
+	return nil
+}
+
+func validateScreenshotFormat(format string) error {
+	switch format {
+	case "", "png", "webp":
+		return nil
+	}
+	return fmt.Errorf("unsupported screenshot format: %s", format)
+}
+
+// refresh stores the media referred from the feed again, to keep them as long as the items.
+// The cached items are stored again on every generation, but their media are not captured again.
+func (s *mediaStore) refresh(feed *feeds.Feed) {
+	refreshed := make(map[string]struct{})
+	texts := make([]string, 0)
+	if feed.Image != nil {
+		texts = append(texts, feed.Image.Url)
+	}
+	for _, item := range feed.Items {
+		texts = append(texts, item.Content, item.Description)
+		if item.Enclosure != nil {
+			texts = append(texts, item.Enclosure.Url)
+		}
+	}
+	for _, text := range texts {
+		for _, match := range mediaURLPattern.FindAllStringSubmatch(text, -1) {
+			id := match[1]
+			if _, exist := refreshed[id]; exist {
+				continue
+			}
+			refreshed[id] = struct{}{}
+			media, err := s.repository.Media.GetMedia(repo.IDKey(id))
+			if err == nil && media != nil {
+				err = s.repository.Media.PutMedia(repo.IDKey(id), media)
+			}
+			if err != nil {
+				log.Printf("Failed to refresh media: id=%s, err=%s", id, err)
+			}
+		}
+	}
+}
+
+// put stores the image, and returns the URL of it.
+// The ID of the image is the hash of the data not to store the same image twice.
+func (s *mediaStore) put(data []byte, format string) (string, error) {
+	hash := sha256.Sum256(data)
+	id := hex.EncodeToString(hash[:16]) + "." + format
+	if err := s.repository.Media.PutMedia(repo.IDKey(id), &repo.Media{
+		ContentType: "image/" + format,
+		Data:        data,
+	}); err != nil {
+		return "", fmt.Errorf("failed to store screenshot: %w", err)
+	}
+	return strings.TrimSuffix(s.baseURL, "/") + "/media/" + id, nil
+}
+
+// captureScreenshot captures the element selected by the JavaScript expression, or the full page if it is empty.
+func captureScreenshot(ctx context.Context, element string, format string) ([]byte, error) {
+	if format == "" {
+		format = defaultScreenshotFormat
+	}
+	script := `(function(){
+	const e = document.documentElement;
+	return {x: 0, y: 0, width: e.scrollWidth, height: e.scrollHeight};
+}())`
+	if element != "" {
+		script = fmt.Sprintf(`(function(){
+	const e = %s;
+	if (!e) {
+		return null;
+	}
+	e.scrollIntoView({block: 'center'});
+	const r = e.getBoundingClientRect();
+	return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+}())`, element)
+	}
+	var clip *page.Viewport
+	if err := chromedp.Evaluate(script, &clip).Do(ctx); err != nil {
+		return nil, err
+	}
+	if clip == nil {
+		return nil, errors.New("element not found")
+	}
+	if clip.Width <= 0 || clip.Height <= 0 {
+		return nil, errors.New("element has no size")
+	}
+	clip.Scale = 1
+	return page.CaptureScreenshot().
+		WithFormat(page.CaptureScreenshotFormat(format)).
+		WithCaptureBeyondViewport(true).
+		WithClip(clip).
+		Do(ctx)
+}
+
+// screenshot builds the 'screenshot' action, which sets the URL of the screenshot as the image of the feed.
+func (c *ScreenshotConfig) screenshot(templateContext *template.TemplateContext, store *mediaStore, setURL func(string)) chromedp.Action {
+	element := ""
+	if c.Selector.IsDefined() {
+		s, _ := json.Marshal(c.Selector.MustEvaluate(templateContext))
+		element = fmt.Sprintf("document.querySelector(%s)", s)
+	}
+	format := c.Format
+	if format == "" {
+		format = defaultScreenshotFormat
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		data, err := captureScreenshot(ctx, element, format)
+		if err != nil {
+			return fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+		url, err := store.put(data, format)
+		if err != nil {
+			return err
+		}
+		setURL(url)
+		return nil
+	})
+}
+
+func newElementScreenshots(ctx context.Context, store *mediaStore, format string) *elementScreenshots {
+	if format == "" {
+		format = defaultScreenshotFormat
+	}
+	return &elementScreenshots{
+		ctx:    ctx,
+		store:  store,
+		format: format,
+		tags:   make(map[*html.Node]string),
+		cache:  make(map[string]string),
+	}
+}
+
+// screenshot captures the element of the captured document, or the full page if no selection is given, and returns the URL.
+// The element is located by the tag which tagElements set before capturing the document.
+func (s *elementScreenshots) screenshot(selections ...*feedtemplate.Selection) (string, error) {
+	tag := ""
+	if len(selections) > 0 {
+		node := selections[0].Node()
+		if node == nil {
+			return "", errors.New("failed to capture screenshot: empty selection")
+		}
+		if tag = s.tags[node]; tag == "" {
+			return "", errors.New("failed to capture screenshot: element not tagged")
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if url, exist := s.cache[tag]; exist {
+		return url, nil
+	}
+	element := ""
+	if tag != "" {
+		selector, _ := json.Marshal(fmt.Sprintf("[%s=%q]", elementTagAttribute, tag))
+		element = fmt.Sprintf("document.querySelector(%s)", selector)
+	}
+	data, err := captureScreenshot(s.ctx, element, s.format)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture screenshot: %w", err)
+	}
+	url, err := s.store.put(data, s.format)
+	if err != nil {
+		return "", err
+	}
+	s.cache[tag] = url
+	return url, nil
+}
+
+// untag removes the tags set by tagElements from the captured document, and keeps them to locate the elements in the page.
+func (s *elementScreenshots) untag(node *html.Node) {
+	if node == nil {
+		return
+	}
+	attrs := node.Attr[:0]
+	for _, attr := range node.Attr {
+		if attr.Key == elementTagAttribute {
+			s.tags[node] = attr.Val
+		} else {
+			attrs = append(attrs, attr)
+		}
+	}
+	node.Attr = attrs
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		s.untag(child)
+	}
+}
+
+// tagElements tags all the elements in the page to find the elements of the captured document in the page.
+func tagElements() chromedp.Action {
+	return chromedp.Evaluate(fmt.Sprintf(`document.querySelectorAll('*').forEach((e, i) => e.setAttribute('%s', String(i)))`, elementTagAttribute), nil)
+}
+
+// usesScreenshot returns true if the templates call the 'Screenshot' func.
+func usesScreenshot(config interface{}) bool {
+	used := false
+	template.WalkTemplateFields(config, func(path string, field template.TemplateField) {
+		if info, err := field.Analyze(); err == nil && info.HasFuncCall("Screenshot") {
+			used = true
+		}
+	})
+	return used
+}
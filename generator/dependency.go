@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencies returns the names of the generators used by the generator, or nil if it is not composite.
+func dependencies(gen FeedGenerator) []string {
+	if composite, ok := gen.(CompositeFeedGenerator); ok && composite.IsComposite() {
+		return composite.Dependencies()
+	}
+	return nil
+}
+
+// dependencyError returns the error if the generator depends on an unknown generator or on itself through the dependencies.
+// dependencies has the dependencies of all the known generators.
+func dependencyError(name string, dependencies map[string][]string) error {
+	for _, dep := range dependencies[name] {
+		if _, exist := dependencies[dep]; !exist {
+			return fmt.Errorf("unknown generator: %s", dep)
+		}
+	}
+
+	visited := make(map[string]struct{})
+	var cycle func(path []string) []string
+	cycle = func(path []string) []string {
+		for _, dep := range dependencies[path[len(path)-1]] {
+			if dep == name {
+				return append(path, dep)
+			}
+			if _, exist := visited[dep]; exist {
+				continue
+			}
+			visited[dep] = struct{}{}
+			if c := cycle(append(path, dep)); c != nil {
+				return c
+			}
+		}
+		return nil
+	}
+	if c := cycle([]string{name}); c != nil {
+		return fmt.Errorf("circular dependency: %s", strings.Join(c, " -> "))
+	}
+	return nil
+}
+
+// checkDependencies returns the first dependency error of the generators.
+func (f *FeedGenerators) checkDependencies() error {
+	deps := make(map[string][]string, len(f.Generators))
+	names := make([]string, 0, len(f.Generators))
+	for name, wrapper := range f.Generators {
+		deps[name] = dependencies(wrapper.generator)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := dependencyError(name, deps); err != nil {
+			return fmt.Errorf("invalid dependency of '%s': %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package generator
+
+import "testing"
+
+func TestDependencyError(t *testing.T) {
+	dependencies := map[string][]string{
+		"a":       nil,
+		"b":       {"a"},
+		"c":       {"b", "a"},
+		"self":    {"self"},
+		"x":       {"y"},
+		"y":       {"z"},
+		"z":       {"x"},
+		"tail":    {"x"},
+		"unknown": {"a", "missing"},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "a"},
+		{name: "b"},
+		{name: "c"},
+		{name: "self", want: "circular dependency: self -> self"},
+		{name: "x", want: "circular dependency: x -> y -> z -> x"},
+		// the generator depending on a cycle is not in the cycle, which is reported on the generators in it
+		{name: "tail"},
+		{name: "unknown", want: "unknown generator: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dependencyError(tt.name, dependencies)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("dependencyError(%q) = %v, want nil", tt.name, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("dependencyError(%q) = %v, want %s", tt.name, err, tt.want)
+			}
+		})
+	}
+}
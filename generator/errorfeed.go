@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// ErrorFeed returns the feed describing the generation error, followed by the last generated items if configured.
+// link is the URL of the feed.
+func (f *FeedGenerators) ErrorFeed(name string, parameters map[string]string, queryParameters url.Values, link string, generationErr error) (*feeds.Feed, error) {
+	wrapper, ok := f.Generators[name]
+	if !ok {
+		return nil, fmt.Errorf("generator not found: %s", name)
+	}
+
+	now := time.Now()
+	feed := &feeds.Feed{
+		Id:          link,
+		Title:       fmt.Sprintf("%s (feedgen)", name),
+		Link:        &feeds.Link{Href: link},
+		Description: fmt.Sprintf("The feed generated by feedgen: generator=%s", name),
+		Created:     now,
+		Updated:     now,
+	}
+	// same error on the same day is reported as the same item not to flood the readers
+	hash := sha256.Sum256([]byte(name + "\n" + generationErr.Error() + "\n" + now.UTC().Format("2006-01-02")))
+	feed.Items = append(feed.Items, &feeds.Item{
+		Id:          fmt.Sprintf("feedgen-error:%s:%x", name, hash[:8]),
+		Title:       fmt.Sprintf("[feedgen] Failed to generate feed: %s", name),
+		Link:        &feeds.Link{Href: link},
+		Description: fmt.Sprintf("Failed to generate the feed: generator=%s, time=%s, err=%s", name, now.Format(time.RFC3339), generationErr),
+		Content: fmt.Sprintf("<p>Failed to generate the feed.</p><ul><li>Generator: %s</li><li>Time: %s</li><li>Error: %s</li></ul>",
+			html.EscapeString(name), now.Format(time.RFC3339), html.EscapeString(generationErr.Error())),
+		Created: now,
+		Updated: now,
+	})
+
+	if wrapper.ErrorFeed != nil && wrapper.ErrorFeed.LastItems > 0 {
+		items, err := f.repository.Item.GetFeedItems(wrapper.FeedKey(parameters, queryParameters), time.Time{}, wrapper.ErrorFeed.LastItems)
+		if err != nil {
+			log.Printf("Failed to get the last items for error feed: name=%s, err=%s", name, err)
+		} else {
+			feed.Items = append(feed.Items, items...)
+		}
+	}
+	return feed, nil
+}
@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/config"
+)
+
+type (
+	// itemFilter drops, deduplicates, sorts and truncates the generated items.
+	itemFilter struct {
+		config  *config.FiltersConfig
+		include []*filterRule
+		exclude []*filterRule
+	}
+	filterRule struct {
+		field   string
+		pattern *regexp.Regexp
+	}
+)
+
+func newItemFilter(c *config.FiltersConfig) (*itemFilter, error) {
+	f := &itemFilter{config: c}
+	var err error
+	if f.include, err = newFilterRules("include", c.Include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = newFilterRules("exclude", c.Exclude); err != nil {
+		return nil, err
+	}
+	if len(c.Dedupe) > 0 {
+		if _, err := itemField(new(feeds.Item), c.Dedupe); err != nil {
+			return nil, fmt.Errorf("invalid 'filters.dedupe': %w", err)
+		}
+	}
+	if c.Sort != nil {
+		switch c.Sort.By {
+		case "created", "updated", "title":
+		default:
+			return nil, fmt.Errorf("invalid 'filters.sort.by': %s", c.Sort.By)
+		}
+		switch c.Sort.Order {
+		case "", "asc", "desc":
+		default:
+			return nil, fmt.Errorf("invalid 'filters.sort.order': %s", c.Sort.Order)
+		}
+	}
+	return f, nil
+}
+
+func newFilterRules(name string, rules []config.FilterRule) ([]*filterRule, error) {
+	result := make([]*filterRule, 0, len(rules))
+	for i, rule := range rules {
+		if _, err := itemField(new(feeds.Item), rule.Field); err != nil {
+			return nil, fmt.Errorf("invalid 'filters.%s[%d].field': %w", name, i, err)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'filters.%s[%d].pattern': %w", name, i, err)
+		}
+		result = append(result, &filterRule{rule.Field, pattern})
+	}
+	return result, nil
+}
+
+func (f *itemFilter) Apply(feed *feeds.Feed) {
+	now := time.Now()
+	items := make([]*feeds.Item, 0, len(feed.Items))
+	seen := make(map[string]struct{})
+	for _, item := range feed.Items {
+		if len(f.include) > 0 && !matchAny(f.include, item) {
+			continue
+		}
+		if matchAny(f.exclude, item) {
+			continue
+		}
+		if t := itemTime(item); !t.IsZero() {
+			if f.config.MinAge > 0 && now.Sub(t) < f.config.MinAge {
+				continue
+			}
+			if f.config.MaxAge > 0 && now.Sub(t) > f.config.MaxAge {
+				continue
+			}
+		}
+		if len(f.config.Dedupe) > 0 {
+			value, _ := itemField(item, f.config.Dedupe)
+			if _, exist := seen[value]; exist {
+				continue
+			}
+			seen[value] = struct{}{}
+		}
+		items = append(items, item)
+	}
+
+	if f.config.Sort != nil {
+		desc := f.config.Sort.Order == "desc"
+		sort.SliceStable(items, func(i, j int) bool {
+			if desc {
+				return lessItem(items[j], items[i], f.config.Sort.By)
+			}
+			return lessItem(items[i], items[j], f.config.Sort.By)
+		})
+	}
+
+	if f.config.MaxItems > 0 && len(items) > f.config.MaxItems {
+		items = items[:f.config.MaxItems]
+	}
+	feed.Items = items
+}
+
+func matchAny(rules []*filterRule, item *feeds.Item) bool {
+	for _, rule := range rules {
+		value, _ := itemField(item, rule.field)
+		if rule.pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func lessItem(a, b *feeds.Item, by string) bool {
+	switch by {
+	case "created":
+		return itemTime(a).Before(itemTime(b))
+	case "updated":
+		return a.Updated.Before(b.Updated)
+	case "title":
+		return strings.Compare(a.Title, b.Title) < 0
+	}
+	return false
+}
+
+func itemField(item *feeds.Item, field string) (string, error) {
+	switch field {
+	case "id":
+		return item.Id, nil
+	case "title":
+		return item.Title, nil
+	case "description":
+		return item.Description, nil
+	case "content":
+		return item.Content, nil
+	case "link":
+		if item.Link == nil {
+			return "", nil
+		}
+		return item.Link.Href, nil
+	case "author":
+		if item.Author == nil {
+			return "", nil
+		}
+		return item.Author.Name, nil
+	}
+	return "", fmt.Errorf("unknown field: %s", field)
+}
+
+func itemTime(item *feeds.Item) time.Time {
+	if item.Created.IsZero() {
+		return item.Updated
+	}
+	return item.Created
+}
@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/config"
+)
+
+func TestItemFilterApply(t *testing.T) {
+	now := time.Now()
+	items := func() []*feeds.Item {
+		return []*feeds.Item{
+			{Id: "1", Title: "Apple", Link: &feeds.Link{Href: "http://example.com/a"}, Created: now.Add(-3 * time.Hour)},
+			{Id: "2", Title: "banana", Link: &feeds.Link{Href: "http://example.com/b"}, Created: now.Add(-10 * time.Minute)},
+			{Id: "3", Title: "Cherry", Link: &feeds.Link{Href: "http://example.com/a"}, Created: now.Add(-48 * time.Hour)},
+			{Id: "4", Title: "Avocado", Updated: now.Add(-1 * time.Hour)},
+			{Id: "5", Title: "Banana bread"},
+		}
+	}
+	tests := []struct {
+		name   string
+		config config.FiltersConfig
+		want   []string
+	}{
+		{name: "no filter", want: []string{"1", "2", "3", "4", "5"}},
+		{
+			name:   "include",
+			config: config.FiltersConfig{Include: []config.FilterRule{{Field: "title", Pattern: "^A"}}},
+			want:   []string{"1", "4"},
+		},
+		{
+			name:   "include any",
+			config: config.FiltersConfig{Include: []config.FilterRule{{Field: "title", Pattern: "^A"}, {Field: "id", Pattern: "^5$"}}},
+			want:   []string{"1", "4", "5"},
+		},
+		{
+			name:   "exclude",
+			config: config.FiltersConfig{Exclude: []config.FilterRule{{Field: "title", Pattern: "(?i)banana"}}},
+			want:   []string{"1", "3", "4"},
+		},
+		{
+			name: "exclude precedes include",
+			config: config.FiltersConfig{
+				Include: []config.FilterRule{{Field: "title", Pattern: "^A"}},
+				Exclude: []config.FilterRule{{Field: "id", Pattern: "^4$"}},
+			},
+			want: []string{"1"},
+		},
+		{
+			name:   "missing link",
+			config: config.FiltersConfig{Include: []config.FilterRule{{Field: "link", Pattern: "/a$"}}},
+			want:   []string{"1", "3"},
+		},
+		{
+			name:   "min age keeps items without time",
+			config: config.FiltersConfig{MinAge: 30 * time.Minute},
+			want:   []string{"1", "3", "4", "5"},
+		},
+		{
+			name:   "max age uses updated without created",
+			config: config.FiltersConfig{MaxAge: 2 * time.Hour},
+			want:   []string{"2", "4", "5"},
+		},
+		{
+			name:   "dedupe keeps first",
+			config: config.FiltersConfig{Dedupe: "link"},
+			want:   []string{"1", "2", "4"},
+		},
+		{
+			name:   "sort by title",
+			config: config.FiltersConfig{Sort: &config.SortConfig{By: "title"}},
+			want:   []string{"1", "4", "5", "3", "2"},
+		},
+		{
+			name:   "sort by created desc",
+			config: config.FiltersConfig{Sort: &config.SortConfig{By: "created", Order: "desc"}},
+			want:   []string{"2", "4", "1", "3", "5"},
+		},
+		{
+			name:   "max items after sort",
+			config: config.FiltersConfig{Sort: &config.SortConfig{By: "title"}, MaxItems: 2},
+			want:   []string{"1", "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			f, err := newItemFilter(&c)
+			if err != nil {
+				t.Fatalf("newItemFilter() failed: %v", err)
+			}
+			feed := &feeds.Feed{Items: items()}
+			f.Apply(feed)
+			got := make([]string, 0, len(feed.Items))
+			for _, item := range feed.Items {
+				got = append(got, item.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewItemFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.FiltersConfig
+	}{
+		{name: "unknown field", config: config.FiltersConfig{Include: []config.FilterRule{{Field: "body", Pattern: "a"}}}},
+		{name: "invalid pattern", config: config.FiltersConfig{Exclude: []config.FilterRule{{Field: "title", Pattern: "("}}}},
+		{name: "unknown dedupe field", config: config.FiltersConfig{Dedupe: "body"}},
+		{name: "unknown sort key", config: config.FiltersConfig{Sort: &config.SortConfig{By: "id"}}},
+		{name: "unknown sort order", config: config.FiltersConfig{Sort: &config.SortConfig{By: "title", Order: "up"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			if _, err := newItemFilter(&c); err == nil {
+				t.Errorf("newItemFilter() succeeded, want error")
+			}
+		})
+	}
+}
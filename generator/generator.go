@@ -6,8 +6,14 @@ import (
 	"net/url"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/uphy/feedgen/config"
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/repo"
 	"github.com/uphy/feedgen/template"
 
@@ -18,6 +24,10 @@ type (
 	Context struct {
 		Repository      *repo.Repository
 		TemplateContext *template.TemplateContext
+		// FeedKey identifies the feed generated with the generator name and the parameters.
+		FeedKey repo.Key
+		// Labels identifies the generator in the metrics.
+		Labels metrics.Labels
 	}
 
 	FeedGenerator interface {
@@ -26,9 +36,31 @@ type (
 	}
 
 	FeedGeneratorWrapper struct {
-		Name      string
-		Endpoint  string
-		generator FeedGenerator
+		Name     string
+		Type     string
+		Endpoint string
+		// Parameters is the names of the path parameters in the endpoint.
+		Parameters []string
+		// QueryParameters is the names of the query parameters used in the config.
+		QueryParameters []string
+		// allQueryParameters is true if the config refers to all the query parameters with QueryParams or .QueryParameters.
+		allQueryParameters bool
+		Schedule           *config.ScheduleConfig
+		CacheControl       *config.CacheControlConfig
+		Cache              *config.CacheConfig
+		ErrorFeed          *config.ErrorFeedConfig
+		generator          FeedGenerator
+		filter             *itemFilter
+		semaphore          chan struct{}
+	}
+
+	// CompositeFeedGenerator is a FeedGenerator which generates a feed from the feeds of other generators.
+	// It is excluded from the concurrency limits because it only waits for the other generators.
+	CompositeFeedGenerator interface {
+		FeedGenerator
+		IsComposite() bool
+		// Dependencies returns the names of the generators used by the generator.
+		Dependencies() []string
 	}
 
 	FeedGenerators struct {
@@ -36,6 +68,17 @@ type (
 		Generators      map[string]*FeedGeneratorWrapper
 		repository      *repo.Repository
 		templateContext *template.TemplateContext
+		semaphore       chan struct{}
+		flights         map[string]*flight
+		flightsMutex    sync.Mutex
+		statuses        map[string]*GeneratorStatus
+		statusMutex     sync.Mutex
+	}
+	// flight is an in-flight generation shared by the concurrent identical requests.
+	flight struct {
+		wg   sync.WaitGroup
+		feed *feeds.Feed
+		err  error
 	}
 )
 
@@ -48,10 +91,21 @@ func New(repository *repo.Repository) *FeedGenerators {
 		Generators:      make(map[string]*FeedGeneratorWrapper),
 		repository:      repository,
 		templateContext: template.NewRootTemplateContext(),
+		flights:         make(map[string]*flight),
+		statuses:        make(map[string]*GeneratorStatus),
 	}
 	return f
 }
 
+// SetConcurrency sets the max number of the concurrent generations across all the generators. 0 means unlimited.
+func (f *FeedGenerators) SetConcurrency(concurrency int) {
+	if concurrency > 0 {
+		f.semaphore = make(chan struct{}, concurrency)
+	} else {
+		f.semaphore = nil
+	}
+}
+
 func findPreDefinedGeneratorConfig(name string) (*config.GeneratorConfig, error) {
 	b, err := predefinedGeneratorConfigs.ReadFile(filepath.Join("config", name+".yml"))
 	if err != nil {
@@ -100,10 +154,15 @@ func (f *FeedGenerators) LoadConfig(config *config.Config) error {
 	}
 
 	for generatorName, generatorConfig := range config.Generators {
-		f.loadGeneratorConfig(generatorName, generatorConfig)
+		if generatorConfig == nil {
+			return fmt.Errorf("empty generator config: name=%s", generatorName)
+		}
+		if err := f.loadGeneratorConfig(generatorName, generatorConfig); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return f.checkDependencies()
 }
 
 func (f *FeedGenerators) loadGeneratorConfig(generatorName string, generatorConfig *config.GeneratorConfig) error {
@@ -115,18 +174,164 @@ func (f *FeedGenerators) loadGeneratorConfig(generatorName string, generatorConf
 	if err != nil {
 		return fmt.Errorf("failed to evaluate 'endpoint': endpoint=%v, err=%w", generatorConfig.Endpoint, err)
 	}
-	f.Generators[generatorName] = &FeedGeneratorWrapper{generatorName, endpoint, gen}
+	var filter *itemFilter
+	if generatorConfig.Filters != nil {
+		if filter, err = newItemFilter(generatorConfig.Filters); err != nil {
+			return fmt.Errorf("failed to load 'filters' of '%s': %w", generatorName, err)
+		}
+	}
+	var semaphore chan struct{}
+	if generatorConfig.Concurrency > 0 {
+		semaphore = make(chan struct{}, generatorConfig.Concurrency)
+	}
+	queryParameters, allQueryParameters := queryParameterNames(gen)
+	f.Generators[generatorName] = &FeedGeneratorWrapper{
+		Name:               generatorName,
+		Type:               generatorConfig.Type,
+		Endpoint:           endpoint,
+		Parameters:         parseEndpointParameters(endpoint),
+		QueryParameters:    queryParameters,
+		allQueryParameters: allQueryParameters,
+		Schedule:           generatorConfig.Schedule,
+		CacheControl:       generatorConfig.CacheControl,
+		Cache:              generatorConfig.Cache,
+		ErrorFeed:          generatorConfig.ErrorFeed,
+		generator:          gen,
+		filter:             filter,
+		semaphore:          semaphore,
+	}
 	return nil
 }
 
+var endpointParameterPattern = regexp.MustCompile(`:([^/]+)`)
+
+func parseEndpointParameters(endpoint string) []string {
+	parameters := make([]string, 0)
+	for _, match := range endpointParameterPattern.FindAllStringSubmatch(endpoint, -1) {
+		parameters = append(parameters, match[1])
+	}
+	return parameters
+}
+
+// queryParameterNames returns the names of the query parameters used by QueryParam in the generator config,
+// and whether the config refers to all the query parameters.
+func queryParameterNames(gen FeedGenerator) ([]string, bool) {
+	names := make([]string, 0)
+	v, ok := gen.(ValidatableFeedGenerator)
+	if !ok {
+		// the usage is unknown
+		return names, true
+	}
+	all := false
+	seen := make(map[string]struct{})
+	target, _ := v.ValidationTarget()
+	template.WalkTemplateFields(target, func(path string, field template.TemplateField) {
+		info, err := field.Analyze()
+		if err != nil {
+			return
+		}
+		if info.HasFuncCall("QueryParams") || info.HasField("QueryParameters") {
+			all = true
+		}
+		for _, name := range info.FuncArgs("QueryParam") {
+			if _, exist := seen[name]; !exist {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	})
+	sort.Strings(names)
+	return names, all
+}
+
+func (w *FeedGeneratorWrapper) labels() metrics.Labels {
+	return metrics.Labels{Generator: w.Name, Type: w.Type}
+}
+
+// FilterQueryParameters returns the query parameters used by the generator.
+// The other query parameters don't change the generated feed.
+func (w *FeedGeneratorWrapper) FilterQueryParameters(queryParameters url.Values) url.Values {
+	filtered := make(url.Values)
+	if w.allQueryParameters {
+		for k, v := range queryParameters {
+			filtered[k] = v
+		}
+		return filtered
+	}
+	for _, name := range w.QueryParameters {
+		if v, exist := queryParameters[name]; exist {
+			filtered[name] = v
+		}
+	}
+	return filtered
+}
+
+// FeedKey returns a readable key of the feed generated by the generator with the parameters.
+// The query parameters not used by the generator are ignored.
+func (w *FeedGeneratorWrapper) FeedKey(parameters map[string]string, queryParameters url.Values) repo.Key {
+	params := make(url.Values)
+	for k, v := range parameters {
+		params.Set(k, v)
+	}
+	return repo.IDKey(strings.Join([]string{w.Name, params.Encode(), w.FilterQueryParameters(queryParameters).Encode()}, "?"))
+}
+
+// Generate generates the feed.
+// Concurrent calls with the same name and parameters share a single generation and its result.
 func (f *FeedGenerators) Generate(name string, parameters map[string]string, queryParameters url.Values) (*feeds.Feed, error) {
 	wrapper, ok := f.Generators[name]
 	if !ok {
 		return nil, fmt.Errorf("generator not found: %s", name)
 	}
+
+	// the unused query parameters don't change the feed, so the requests differing only in them share a generation
+	queryParameters = wrapper.FilterQueryParameters(queryParameters)
+	key := wrapper.FeedKey(parameters, queryParameters).Key()
+	f.flightsMutex.Lock()
+	if c, exist := f.flights[key]; exist {
+		f.flightsMutex.Unlock()
+		c.wg.Wait()
+		return c.feed, c.err
+	}
+	c := new(flight)
+	c.wg.Add(1)
+	f.flights[key] = c
+	f.flightsMutex.Unlock()
+
+	start := time.Now()
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				c.feed, c.err = nil, fmt.Errorf("failed to generate: %v", rec)
+			}
+			f.recordStatus(name, start, c.feed, c.err)
+			items := 0
+			if c.feed != nil {
+				items = len(c.feed.Items)
+			}
+			metrics.ObserveGeneration(wrapper.labels(), time.Since(start), items, c.err)
+			f.flightsMutex.Lock()
+			delete(f.flights, key)
+			f.flightsMutex.Unlock()
+			c.wg.Done()
+		}()
+		c.feed, c.err = f.generate(wrapper, parameters, queryParameters)
+	}()
+	return c.feed, c.err
+}
+
+func (f *FeedGenerators) generate(wrapper *FeedGeneratorWrapper, parameters map[string]string, queryParameters url.Values) (*feeds.Feed, error) {
 	gen := wrapper.generator
+	if composite, ok := gen.(CompositeFeedGenerator); !ok || !composite.IsComposite() {
+		for _, semaphore := range []chan struct{}{wrapper.semaphore, f.semaphore} {
+			if semaphore != nil {
+				semaphore <- struct{}{}
+				defer func(semaphore chan struct{}) { <-semaphore }(semaphore)
+			}
+		}
+	}
 
-	context := &Context{f.repository, f.templateContext.Child()}
+	context := &Context{f.repository, f.templateContext.Child(), wrapper.FeedKey(parameters, queryParameters), wrapper.labels()}
 	context.TemplateContext.Set("Parameters", parameters)
 	context.TemplateContext.Set("QueryParameters", queryParameters)
 	context.TemplateContext.AddFuncs(map[string]interface{}{
@@ -151,6 +356,9 @@ func (f *FeedGenerators) Generate(name string, parameters map[string]string, que
 		},
 	})
 	if feed, err := gen.Generate(context); err == nil {
+		if wrapper.filter != nil {
+			wrapper.filter.Apply(feed)
+		}
 		return feed, nil
 	} else {
 		return nil, err
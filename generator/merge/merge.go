@@ -0,0 +1,180 @@
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/config"
+	"github.com/uphy/feedgen/generator"
+	"github.com/uphy/feedgen/template"
+)
+
+type (
+	MergeFeedGeneratorConfig struct {
+		Feed struct {
+			ID          template.TemplateField `yaml:"id"`
+			Title       template.TemplateField `yaml:"title"`
+			Description template.TemplateField `yaml:"description"`
+			Link        template.TemplateField `yaml:"link"`
+		} `yaml:"feed"`
+		Sources []SourceConfig `yaml:"sources"`
+		Limit   int            `yaml:"limit"`
+	}
+	SourceConfig struct {
+		// Generator is the name of the generator to merge.
+		Generator       string                            `yaml:"generator"`
+		Parameters      map[string]template.TemplateField `yaml:"parameters"`
+		QueryParameters map[string]template.TemplateField `yaml:"queryParameters"`
+	}
+	// MergeFeedGenerator merges the feeds generated by other generators into one feed.
+	MergeFeedGenerator struct {
+		generators *generator.FeedGenerators
+		config     *MergeFeedGeneratorConfig
+	}
+)
+
+func New(generators *generator.FeedGenerators) *MergeFeedGenerator {
+	return &MergeFeedGenerator{generators: generators}
+}
+
+func (g *MergeFeedGenerator) LoadOptions(options config.GeneratorOptions) error {
+	var c MergeFeedGeneratorConfig
+	if err := options.Unmarshal(&c); err != nil {
+		return err
+	}
+	if len(c.Sources) == 0 {
+		return errors.New("'sources' is required")
+	}
+	for i, s := range c.Sources {
+		if s.Generator == "" {
+			return fmt.Errorf("'sources[%d].generator' is required", i)
+		}
+	}
+	g.config = &c
+	return nil
+}
+
+func (g *MergeFeedGenerator) IsComposite() bool {
+	return true
+}
+
+func (g *MergeFeedGenerator) Dependencies() []string {
+	names := make([]string, 0, len(g.config.Sources))
+	for _, s := range g.config.Sources {
+		names = append(names, s.Generator)
+	}
+	return names
+}
+
+func (g *MergeFeedGenerator) ValidationTarget() (interface{}, []string) {
+	return g.config, nil
+}
+
+func (g *MergeFeedGenerator) Generate(context *generator.Context) (*feeds.Feed, error) {
+	templateContext := context.TemplateContext
+
+	feed := new(feeds.Feed)
+	var err error
+	if feed.Id, err = g.config.Feed.ID.Evaluate(templateContext); err != nil {
+		return nil, fmt.Errorf("failed to evaluate 'feed.id': %w", err)
+	}
+	if feed.Title, err = g.config.Feed.Title.Evaluate(templateContext); err != nil {
+		return nil, fmt.Errorf("failed to evaluate 'feed.title': %w", err)
+	}
+	if feed.Description, err = g.config.Feed.Description.Evaluate(templateContext); err != nil {
+		return nil, fmt.Errorf("failed to evaluate 'feed.description': %w", err)
+	}
+	if link, err := g.config.Feed.Link.Evaluate(templateContext); err == nil {
+		if len(link) > 0 {
+			feed.Link = &feeds.Link{Href: link}
+		}
+	} else {
+		return nil, fmt.Errorf("failed to evaluate 'feed.link': %w", err)
+	}
+
+	items := make([]*feeds.Item, 0)
+	failed := 0
+	for i, source := range g.config.Sources {
+		f, err := g.generateSource(templateContext, &source)
+		if err != nil {
+			log.Printf("Failed to generate a source of merged feed: sources[%d].generator=%s, err=%s", i, source.Generator, err)
+			failed++
+			continue
+		}
+		items = append(items, f.Items...)
+		if feed.Created.IsZero() || f.Created.Before(feed.Created) {
+			feed.Created = f.Created
+		}
+	}
+	if failed == len(g.config.Sources) {
+		return nil, errors.New("failed to generate all sources")
+	}
+
+	items = dedupe(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return itemTime(items[i]).After(itemTime(items[j]))
+	})
+	if g.config.Limit > 0 && len(items) > g.config.Limit {
+		items = items[:g.config.Limit]
+	}
+	feed.Items = items
+	if len(items) > 0 {
+		feed.Updated = itemTime(items[0])
+	} else {
+		feed.Updated = time.Now()
+	}
+	return feed, nil
+}
+
+func (g *MergeFeedGenerator) generateSource(context *template.TemplateContext, source *SourceConfig) (*feeds.Feed, error) {
+	parameters := make(map[string]string)
+	for k, v := range source.Parameters {
+		s, err := v.Evaluate(context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate 'parameters.%s': %w", k, err)
+		}
+		parameters[k] = s
+	}
+	queryParameters := make(url.Values)
+	for k, v := range source.QueryParameters {
+		s, err := v.Evaluate(context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate 'queryParameters.%s': %w", k, err)
+		}
+		queryParameters.Set(k, s)
+	}
+	return g.generators.Generate(source.Generator, parameters, queryParameters)
+}
+
+// dedupe removes the items which have the same ID as a preceding item.
+// Items without ID are identified by the link.
+func dedupe(items []*feeds.Item) []*feeds.Item {
+	seen := make(map[string]struct{})
+	result := make([]*feeds.Item, 0, len(items))
+	for _, item := range items {
+		id := item.Id
+		if len(id) == 0 && item.Link != nil {
+			id = item.Link.Href
+		}
+		if len(id) > 0 {
+			if _, exist := seen[id]; exist {
+				continue
+			}
+			seen[id] = struct{}{}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func itemTime(item *feeds.Item) time.Time {
+	if item.Created.IsZero() {
+		return item.Updated
+	}
+	return item.Created
+}
@@ -1,50 +1,189 @@
 package source
 
 import (
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/template"
 )
 
 type (
 	httpSourceHandler struct {
-		URL template.TemplateField `yaml:"url"`
-
-		context *template.TemplateContext
-		url     string
+		URL       template.TemplateField            `yaml:"url"`
+		Method    template.TemplateField            `yaml:"method"`
+		Headers   map[string]template.TemplateField `yaml:"headers"`
+		Cookies   map[string]template.TemplateField `yaml:"cookies"`
+		Body      template.TemplateField            `yaml:"body"`
+		Form      map[string]template.TemplateField `yaml:"form"`
+		UserAgent template.TemplateField            `yaml:"userAgent"`
+	}
+	// httpRequest is the http request evaluated for a generation.
+	httpRequest struct {
+		labels metrics.Labels
+		url    string
+		// host is the host of the source URL, to which the headers and cookies are sent.
+		host      string
+		method    string
+		headers   http.Header
+		cookies   []*http.Cookie
+		body      string
+		userAgent string
 	}
 	httpSourceConfigYAML struct {
-		URL template.TemplateField `yaml:"url"`
+		URL       template.TemplateField            `yaml:"url"`
+		Method    template.TemplateField            `yaml:"method"`
+		Headers   map[string]template.TemplateField `yaml:"headers"`
+		Cookies   map[string]template.TemplateField `yaml:"cookies"`
+		Body      template.TemplateField            `yaml:"body"`
+		Form      map[string]template.TemplateField `yaml:"form"`
+		UserAgent template.TemplateField            `yaml:"userAgent"`
 	}
 )
 
-func (c *httpSourceHandler) Init(context *template.TemplateContext) error {
-	c.context = context
+const defaultUserAgent = "Mozilla/5.0 (compatible; feedgen; +https://github.com/uphy/feedgen)"
+
+func (c *httpSourceHandler) Init(context *template.TemplateContext, labels metrics.Labels) (Request, error) {
+	r := &httpRequest{labels: labels}
 	if s, err := c.URL.Evaluate(context); err == nil {
-		c.url = s
+		r.url = s
 	} else {
-		return err
+		return nil, err
 	}
-	return nil
+	if u, err := url.Parse(r.url); err == nil {
+		r.host = u.Host
+	}
+
+	r.headers = make(http.Header)
+	for name, value := range c.Headers {
+		if s, err := value.Evaluate(context); err == nil {
+			r.headers.Set(name, s)
+		} else {
+			return nil, fmt.Errorf("failed to evaluate 'headers.%s': %w", name, err)
+		}
+	}
+
+	r.cookies = make([]*http.Cookie, 0, len(c.Cookies))
+	for name, value := range c.Cookies {
+		if s, err := value.Evaluate(context); err == nil {
+			r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: s})
+		} else {
+			return nil, fmt.Errorf("failed to evaluate 'cookies.%s': %w", name, err)
+		}
+	}
+
+	if c.Body.IsDefined() {
+		if s, err := c.Body.Evaluate(context); err == nil {
+			r.body = s
+		} else {
+			return nil, fmt.Errorf("failed to evaluate 'body': %w", err)
+		}
+	} else if len(c.Form) > 0 {
+		form := make(url.Values)
+		for name, value := range c.Form {
+			if s, err := value.Evaluate(context); err == nil {
+				form.Set(name, s)
+			} else {
+				return nil, fmt.Errorf("failed to evaluate 'form.%s': %w", name, err)
+			}
+		}
+		r.body = form.Encode()
+		if r.headers.Get("Content-Type") == "" {
+			r.headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	r.method = http.MethodGet
+	if c.Method.IsDefined() {
+		if s, err := c.Method.Evaluate(context); err == nil {
+			r.method = strings.ToUpper(s)
+		} else {
+			return nil, fmt.Errorf("failed to evaluate 'method': %w", err)
+		}
+	} else if c.Body.IsDefined() || len(c.Form) > 0 {
+		r.method = http.MethodPost
+	}
+
+	r.userAgent = defaultUserAgent
+	if c.UserAgent.IsDefined() {
+		if s, err := c.UserAgent.Evaluate(context); err == nil {
+			r.userAgent = s
+		} else {
+			return nil, fmt.Errorf("failed to evaluate 'userAgent': %w", err)
+		}
+	}
+	return r, nil
 }
 
-func (c *httpSourceHandler) GetURL() string {
-	return c.URL.MustEvaluate(c.context)
+func (r *httpRequest) GetURL() string {
+	return r.url
+}
+
+func (r *httpRequest) Open() (io.ReadCloser, error) {
+	return r.OpenPage(r.url)
+}
+
+func (r *httpRequest) OpenPage(url string) (io.ReadCloser, error) {
+	var body io.Reader
+	if len(r.body) > 0 {
+		body = strings.NewReader(r.body)
+	}
+	return r.do(r.method, url, body)
 }
 
-func (c *httpSourceHandler) Open() (io.ReadCloser, error) {
-	req, err := http.Get(c.url)
+func (r *httpRequest) OpenURL(url string) (io.ReadCloser, error) {
+	return r.do(http.MethodGet, url, nil)
+}
+
+func (r *httpRequest) do(method string, url string, body io.Reader) (io.ReadCloser, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+	// the headers and cookies may be the credentials for the source, so they are not sent to the other hosts such as the item links
+	if strings.EqualFold(req.URL.Host, r.host) {
+		for name, values := range r.headers {
+			if body == nil && name == "Content-Type" {
+				continue
+			}
+			req.Header[name] = values
+		}
+		for _, cookie := range r.cookies {
+			req.AddCookie(cookie)
+		}
+	} else if contentType := r.headers.Get("Content-Type"); body != nil && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		metrics.ObserveSourceFetch(r.labels, time.Since(start), 0)
 		return nil, err
 	}
-	return req.Body, nil
+	metrics.ObserveSourceFetch(r.labels, time.Since(start), resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: url=%s, status=%s", url, resp.Status)
+	}
+	return resp.Body, nil
 }
 
 func (c *httpSourceHandler) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var y httpSourceConfigYAML
 	if err := unmarshal(&y); err == nil {
 		c.URL = y.URL
+		c.Method = y.Method
+		c.Headers = y.Headers
+		c.Cookies = y.Cookies
+		c.Body = y.Body
+		c.Form = y.Form
+		c.UserAgent = y.UserAgent
 	} else {
 		var s string
 		if err := unmarshal(&s); err == nil {
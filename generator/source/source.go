@@ -1,22 +1,53 @@
 package source
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/template"
 )
 
 type (
 	Source struct {
 		HTTP *httpSourceHandler `yaml:"http"`
+		// Format is the format of the source document. "html"(default) or "json".
+		Format string `yaml:"format"`
 	}
 	sourceHandler interface {
-		Init(context *template.TemplateContext) error
+		Init(context *template.TemplateContext, labels metrics.Labels) (Request, error)
+	}
+	// Request is the request to the source evaluated for a generation.
+	// The source config is shared by the concurrent generations, so the evaluated values are kept in the request.
+	Request interface {
 		GetURL() string
 		Open() (io.ReadCloser, error)
+		// OpenPage opens another page of the source, such as the next page, with the same request as the source.
+		OpenPage(url string) (io.ReadCloser, error)
+		// OpenURL opens another URL, such as a detail page of an item, with the same headers, cookies and user agent.
+		OpenURL(url string) (io.ReadCloser, error)
 	}
 )
 
+const (
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+// Validate reports the invalid source config on loading the config file, not on generation.
+func (s *Source) Validate() error {
+	if s.HTTP == nil {
+		return errors.New("'source.http' is required")
+	}
+	switch format := s.GetFormat(); format {
+	case FormatHTML, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported source format: %s", format)
+	}
+}
+
 func (s *Source) source() sourceHandler {
 	if s.HTTP != nil {
 		return s.HTTP
@@ -24,10 +55,14 @@ func (s *Source) source() sourceHandler {
 	panic("invalid source")
 }
 
-func (s *Source) GetURL() string {
-	return s.source().GetURL()
+// Init evaluates the source config, and returns the request for the generation.
+func (s *Source) Init(context *template.TemplateContext, labels metrics.Labels) (Request, error) {
+	return s.source().Init(context, labels)
 }
 
-func (s *Source) Open() (io.ReadCloser, error) {
-	return s.source().Open()
+func (s *Source) GetFormat() string {
+	if s.Format == "" {
+		return FormatHTML
+	}
+	return s.Format
 }
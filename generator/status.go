@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+type (
+	// GeneratorStatus is the result of the last generations of a generator.
+	GeneratorStatus struct {
+		LastGenerated       time.Time
+		Duration            time.Duration
+		Items               int
+		LastError           string
+		LastErrorAt         time.Time
+		ConsecutiveFailures int
+	}
+)
+
+func (f *FeedGenerators) recordStatus(name string, start time.Time, feed *feeds.Feed, err error) {
+	f.statusMutex.Lock()
+	defer f.statusMutex.Unlock()
+	status, exist := f.statuses[name]
+	if !exist {
+		status = &GeneratorStatus{}
+		f.statuses[name] = status
+	}
+	status.LastGenerated = start
+	status.Duration = time.Since(start)
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastErrorAt = start
+		status.ConsecutiveFailures++
+		status.Items = 0
+		return
+	}
+	status.ConsecutiveFailures = 0
+	if feed != nil {
+		status.Items = len(feed.Items)
+	}
+}
+
+// Status returns the status of the generator, or nil if it has not generated any feed yet.
+func (f *FeedGenerators) Status(name string) *GeneratorStatus {
+	f.statusMutex.Lock()
+	defer f.statusMutex.Unlock()
+	if status, exist := f.statuses[name]; exist {
+		s := *status
+		return &s
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tmpl "github.com/uphy/feedgen/template"
+)
+
+type (
+	// DateConfig is a template field evaluated to a time.
+	DateConfig struct {
+		Value tmpl.TemplateField `yaml:"value"`
+		// Layouts are the Go time layouts tried before the default layouts.
+		Layouts []string `yaml:"layouts"`
+		// Timezone is the location name used for times without time zone.
+		Timezone string `yaml:"timezone"`
+	}
+	dateConfigYAML struct {
+		Value    tmpl.TemplateField `yaml:"value"`
+		Layouts  []string           `yaml:"layouts"`
+		Timezone string             `yaml:"timezone"`
+	}
+)
+
+// errUnparsableTime is wrapped by the error of the evaluated time which cannot be parsed.
+var errUnparsableTime = errors.New("unparsable time")
+
+// Evaluate evaluates the time, returns zero time if not defined or evaluated to empty.
+func (d *DateConfig) Evaluate(context *tmpl.TemplateContext) (time.Time, error) {
+	if !d.Value.IsDefined() {
+		return time.Time{}, nil
+	}
+	s, err := d.Value.Evaluate(context)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	location := time.Local
+	if len(d.Timezone) > 0 {
+		if location, err = time.LoadLocation(d.Timezone); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	t, err := tmpl.ParseTime(s, d.Layouts, location, time.Now())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", errUnparsableTime, err)
+	}
+	return t, nil
+}
+
+func (d *DateConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var y dateConfigYAML
+	if err := unmarshal(&y); err == nil {
+		d.Value = y.Value
+		d.Layouts = y.Layouts
+		d.Timezone = y.Timezone
+	} else {
+		var s string
+		if err := unmarshal(&s); err == nil {
+			d.Value = tmpl.NewTemplateField(s)
+		} else {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type (
+	// JSONValue is a part of JSON document selected by a gjson path.
+	// See https://github.com/tidwall/gjson/blob/master/SYNTAX.md for the path syntax.
+	JSONValue struct {
+		result gjson.Result
+	}
+)
+
+func newJSONValueFromReader(reader io.Reader) (*JSONValue, error) {
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if !gjson.ValidBytes(b) {
+		return nil, fmt.Errorf("failed to load document: invalid JSON")
+	}
+	return &JSONValue{gjson.ParseBytes(b)}, nil
+}
+
+// List returns the elements of the array at the path.
+// Use "@this" to list the elements of the root array.
+func (v *JSONValue) List(path string) ([]*JSONValue, error) {
+	result := v.result.Get(path)
+	if !result.Exists() {
+		return []*JSONValue{}, nil
+	}
+	if !result.IsArray() {
+		return nil, fmt.Errorf("not an array: path=%s", path)
+	}
+	values := make([]*JSONValue, 0)
+	result.ForEach(func(key, value gjson.Result) bool {
+		values = append(values, &JSONValue{value})
+		return true
+	})
+	return values, nil
+}
+
+func (v *JSONValue) itemContents(path string) ([]interface{}, error) {
+	values, err := v.List(path)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]interface{}, len(values))
+	for i, value := range values {
+		contents[i] = value
+	}
+	return contents, nil
+}
+
+func (v *JSONValue) link(path string) string {
+	return strings.TrimSpace(v.result.Get(path).String())
+}
+
+func (v *JSONValue) Get(path string) *JSONValue {
+	return &JSONValue{v.result.Get(path)}
+}
+
+func (v *JSONValue) Exist() bool {
+	return v.result.Exists()
+}
+
+func (v *JSONValue) Int() int64 {
+	return v.result.Int()
+}
+
+func (v *JSONValue) Float() float64 {
+	return v.result.Float()
+}
+
+func (v *JSONValue) Bool() bool {
+	return v.result.Bool()
+}
+
+// Raw returns the raw JSON text of the value.
+func (v *JSONValue) Raw() string {
+	return v.result.Raw
+}
+
+func (v *JSONValue) Text() string {
+	return v.result.String()
+}
+
+func (v *JSONValue) String() string {
+	return v.Text()
+}
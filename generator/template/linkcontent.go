@@ -0,0 +1,140 @@
+package template
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/uphy/feedgen/generator/source"
+	"github.com/uphy/feedgen/repo"
+	tmpl "github.com/uphy/feedgen/template"
+)
+
+type (
+	// LinkContentConfig configures fetching the linked pages of the items for .LinkContent in advance.
+	LinkContentConfig struct {
+		// Workers is the max number of the concurrent fetches. Defaults to 4.
+		Workers int `yaml:"workers"`
+		// RatePerHost is the max number of the fetches per second to a host. 0 means unlimited.
+		// The limit is shared by all the generators fetching from the host.
+		RatePerHost float64 `yaml:"ratePerHost"`
+	}
+	// hostRateLimiter spaces the requests to the same host.
+	hostRateLimiter struct {
+		next  map[string]time.Time
+		mutex sync.Mutex
+	}
+)
+
+// linkContentRateLimiter is shared by all the generations not to exceed the rate across the feeds.
+var linkContentRateLimiter = &hostRateLimiter{next: make(map[string]time.Time)}
+
+const defaultLinkContentWorkers = 4
+
+func (c *LinkContentConfig) validate() error {
+	if c.Workers < 0 {
+		return errors.New("'linkContent.workers' must be a non-negative integer")
+	}
+	if c.RatePerHost < 0 {
+		return errors.New("'linkContent.ratePerHost' must be a non-negative number")
+	}
+	return nil
+}
+
+// wait blocks until a request to the host is allowed at the rate.
+func (l *hostRateLimiter) wait(host string, ratePerHost float64) {
+	if ratePerHost <= 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / ratePerHost)
+	l.mutex.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+		// forget the hosts not requested recently not to grow the map
+		for h, next := range l.next {
+			if next.Before(now) {
+				delete(l.next, h)
+			}
+		}
+	}
+	l.next[host] = at.Add(interval)
+	l.mutex.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+// prefetchLinkContents fetches the linked pages of the items not cached in the repository concurrently.
+// It returns the .LinkContent of each item, or nil for the items which are not fetched.
+func (g *TemplateFeedGenerator) prefetchLinkContents(context *tmpl.TemplateContext, repository *repo.Repository, request source.Request, itemContents []interface{}, pageURLs []string) []*Selection {
+	linkContents := make([]*Selection, len(itemContents))
+	c := g.config.LinkContent
+	if c == nil || request == nil || !g.config.Item.Link.HREF.IsDefined() {
+		return linkContents
+	}
+	// the items can't be identified before fetching the linked pages
+	if usesField(g.config.Item.ID, "LinkContent") || usesField(g.config.Item.Link.HREF, "LinkContent") {
+		return linkContents
+	}
+
+	type fetch struct {
+		index int
+		url   string
+	}
+	fetches := make([]fetch, 0, len(itemContents))
+	for i, itemContent := range itemContents {
+		itemContext := newItemContext(context)
+		itemContext.Set("URL", pageURLs[i])
+		itemContext.Set("ItemContent", itemContent)
+		id, err := g.itemID(itemContext)
+		if err != nil {
+			// reported on loading the item
+			continue
+		}
+		// the cached items don't use .LinkContent unless they are re-evaluated for update
+		if !g.config.Update.enabled() {
+			if item, err := repository.Item.GetFeedItem(repo.IDKey(id)); err != nil || item != nil {
+				continue
+			}
+		}
+		if link := g.mustEvaluateItemLink(itemContext); len(link) > 0 {
+			fetches = append(fetches, fetch{i, link})
+		}
+	}
+
+	workers := c.Workers
+	if workers == 0 {
+		workers = defaultLinkContentWorkers
+	}
+	ch := make(chan fetch)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range ch {
+				if u, err := url.Parse(f.url); err == nil {
+					linkContentRateLimiter.wait(u.Host, c.RatePerHost)
+				}
+				selection, err := loadDocument(request, f.url)
+				linkContents[f.index] = newSelectionFromFactory(func() (*goquery.Selection, error) {
+					return selection, err
+				})
+			}
+		}()
+	}
+	for _, f := range fetches {
+		ch <- f
+	}
+	close(ch)
+	wg.Wait()
+	return linkContents
+}
+
+// usesField returns true if the template refers to the field.
+func usesField(field tmpl.TemplateField, name string) bool {
+	info, err := field.Analyze()
+	return err == nil && info.HasField(name)
+}
@@ -0,0 +1,204 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/uphy/feedgen/generator/source"
+	"github.com/uphy/feedgen/repo"
+	tmpl "github.com/uphy/feedgen/template"
+)
+
+type (
+	// PaginationConfig configures following the pages of the source.
+	// One of Next, URL and Offset is required.
+	// The pages are requested with the same method and body as the source, and .URL of the items is the URL of their page.
+	PaginationConfig struct {
+		// Next is the selector of the link to the next page, or the path of the next page URL for JSON source.
+		Next tmpl.TemplateField `yaml:"next"`
+		// URL is the URL of the page evaluated with .Page, the page number starting from 2.
+		URL tmpl.TemplateField `yaml:"url"`
+		// Offset sets the offset of the first item of the page to the query parameter of the source URL.
+		Offset *OffsetConfig `yaml:"offset"`
+		// MaxPages is the max number of the pages including the first page. Defaults to 10.
+		MaxPages int `yaml:"maxPages"`
+		// StopWhenKnown stops following the pages when all the items in the page are already known.
+		StopWhenKnown bool `yaml:"stopWhenKnown"`
+	}
+	OffsetConfig struct {
+		// Parameter is the name of the query parameter.
+		Parameter string `yaml:"parameter"`
+		// Start is the offset of the first page.
+		Start int `yaml:"start"`
+		// Step is the offset increment per page. Defaults to the number of the items in the first page.
+		Step int `yaml:"step"`
+	}
+)
+
+const defaultMaxPages = 10
+
+func (c *PaginationConfig) validate() error {
+	n := 0
+	if c.Next.IsDefined() {
+		n++
+	}
+	if c.URL.IsDefined() {
+		n++
+	}
+	if c.Offset != nil {
+		n++
+		if c.Offset.Parameter == "" {
+			return errors.New("'pagination.offset.parameter' is required")
+		}
+	}
+	if n != 1 {
+		return errors.New("one of 'pagination.next', 'pagination.url' and 'pagination.offset' is required")
+	}
+	if c.MaxPages < 0 {
+		return errors.New("'pagination.maxPages' must be a non-negative integer")
+	}
+	return nil
+}
+
+func (c *PaginationConfig) maxPages() int {
+	if c.MaxPages == 0 {
+		return defaultMaxPages
+	}
+	return c.MaxPages
+}
+
+// loadItemContents returns the item contents in the source document, followed by the ones in the next pages if pagination is configured.
+// It also returns the URLs of the pages where the items are found.
+func (g *TemplateFeedGenerator) loadItemContents(context *tmpl.TemplateContext, repository *repo.Repository, request source.Request, baseURL *url.URL, doc document) ([]interface{}, []string, error) {
+	list := g.config.List.MustEvaluate(context)
+	contents, err := doc.itemContents(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	pageURLs := repeat(baseURL.String(), len(contents))
+	pagination := g.config.Pagination
+	if pagination == nil || request == nil {
+		return contents, pageURLs, nil
+	}
+
+	pageSize := len(contents)
+	pageContents := contents
+	pageURL := baseURL
+	visited := map[string]struct{}{baseURL.String(): {}}
+	for page := 2; page <= pagination.maxPages(); page++ {
+		if len(pageContents) == 0 {
+			break
+		}
+		if g.config.Limit > 0 && len(contents) >= g.config.Limit {
+			break
+		}
+		if pagination.StopWhenKnown {
+			known, err := g.allKnown(context, repository, pageURL.String(), pageContents)
+			if err != nil {
+				return nil, nil, err
+			}
+			if known {
+				break
+			}
+		}
+
+		nextURL, err := g.nextPageURL(context, baseURL, pageURL, doc, page, pageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		if nextURL == nil {
+			break
+		}
+		if _, exist := visited[nextURL.String()]; exist {
+			break
+		}
+		visited[nextURL.String()] = struct{}{}
+
+		if doc, err = g.loadPage(request, nextURL.String()); err != nil {
+			return nil, nil, fmt.Errorf("failed to load page: page=%d, url=%s, err=%w", page, nextURL, err)
+		}
+		if pageContents, err = doc.itemContents(list); err != nil {
+			return nil, nil, err
+		}
+		pageURL = nextURL
+		contents = append(contents, pageContents...)
+		pageURLs = append(pageURLs, repeat(pageURL.String(), len(pageContents))...)
+	}
+	return contents, pageURLs, nil
+}
+
+func repeat(s string, n int) []string {
+	a := make([]string, n)
+	for i := range a {
+		a[i] = s
+	}
+	return a
+}
+
+// nextPageURL returns the URL of the page, or nil if there is no more page.
+func (g *TemplateFeedGenerator) nextPageURL(context *tmpl.TemplateContext, baseURL *url.URL, pageURL *url.URL, doc document, page int, pageSize int) (*url.URL, error) {
+	pagination := g.config.Pagination
+	var next string
+	switch {
+	case pagination.Next.IsDefined():
+		next = doc.link(pagination.Next.MustEvaluate(context))
+	case pagination.URL.IsDefined():
+		pageContext := context.Child()
+		pageContext.Set("Page", page)
+		s, err := pagination.URL.Evaluate(pageContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate 'pagination.url': %w", err)
+		}
+		next = s
+	case pagination.Offset != nil:
+		step := pagination.Offset.Step
+		if step <= 0 {
+			step = pageSize
+		}
+		u := *baseURL
+		query := u.Query()
+		query.Set(pagination.Offset.Parameter, strconv.Itoa(pagination.Offset.Start+(page-1)*step))
+		u.RawQuery = query.Encode()
+		return &u, nil
+	}
+	if len(next) == 0 {
+		return nil, nil
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next page URL: %w", err)
+	}
+	return pageURL.ResolveReference(u), nil
+}
+
+func (g *TemplateFeedGenerator) loadPage(request source.Request, url string) (document, error) {
+	reader, err := request.OpenPage(url)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return g.parseDocument(reader)
+}
+
+// allKnown returns true if all the items in the page are found in the repository.
+func (g *TemplateFeedGenerator) allKnown(context *tmpl.TemplateContext, repository *repo.Repository, pageURL string, itemContents []interface{}) (bool, error) {
+	for _, itemContent := range itemContents {
+		itemContext := newItemContext(context)
+		itemContext.Set("URL", pageURL)
+		itemContext.Set("ItemContent", itemContent)
+		id, err := g.itemID(itemContext)
+		if err != nil {
+			return false, err
+		}
+		item, err := repository.Item.GetFeedItem(repo.IDKey(id))
+		if err != nil {
+			return false, err
+		}
+		if item == nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -3,9 +3,11 @@ package template
 import (
 	"fmt"
 	"io"
-	"net/http"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/uphy/feedgen/generator/source"
+	"golang.org/x/net/html"
 )
 
 type (
@@ -15,13 +17,13 @@ type (
 	}
 )
 
-func loadDocument(url string) (*goquery.Selection, error) {
-	resp, err := http.Get(url)
+func loadDocument(src source.Request, url string) (*goquery.Selection, error) {
+	body, err := src.OpenURL(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed on GET request: %w", err)
 	}
-	defer resp.Body.Close()
-	return loadDocumentFromReader(resp.Body)
+	defer body.Close()
+	return loadDocumentFromReader(body)
 }
 
 func loadDocumentFromReader(reader io.Reader) (*goquery.Selection, error) {
@@ -71,6 +73,22 @@ func (d *Selection) List(selector string) ([]*Selection, error) {
 	return selections, nil
 }
 
+func (d *Selection) itemContents(selector string) ([]interface{}, error) {
+	selections, err := d.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]interface{}, len(selections))
+	for i, s := range selections {
+		contents[i] = s
+	}
+	return contents, nil
+}
+
+func (d *Selection) link(selector string) string {
+	return strings.TrimSpace(d.selection().Find(selector).First().AttrOr("href", ""))
+}
+
 func (d *Selection) Select(selector string) *Selection {
 	return &Selection{cache: d.selection().Find(selector)}
 }
@@ -90,6 +108,14 @@ func (d *Selection) First() *Selection {
 	return newSelection(d.selection().First())
 }
 
+// Node returns the first node of the selection, or nil if empty.
+func (d *Selection) Node() *html.Node {
+	if nodes := d.selection().Nodes; len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
 func (d *Selection) HTML() (string, error) {
 	return d.selection().Html()
 }
@@ -3,7 +3,11 @@ package template
 import (
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
@@ -11,6 +15,7 @@ import (
 	"github.com/uphy/feedgen/config"
 	"github.com/uphy/feedgen/generator"
 	"github.com/uphy/feedgen/generator/source"
+	"github.com/uphy/feedgen/metrics"
 	"github.com/uphy/feedgen/repo"
 	tmpl "github.com/uphy/feedgen/template"
 
@@ -19,11 +24,24 @@ import (
 
 type (
 	TemplateFeedGeneratorConfig struct {
-		Source *source.Source     `yaml:"source"`
-		Feed   FeedConfig         `yaml:"feed"`
-		List   tmpl.TemplateField `yaml:"list"`
-		Item   ItemConfig         `yaml:"item"`
-		Limit  int                `yaml:"limit"`
+		Source     *source.Source     `yaml:"source"`
+		Feed       FeedConfig         `yaml:"feed"`
+		List       tmpl.TemplateField `yaml:"list"`
+		Item       ItemConfig         `yaml:"item"`
+		Limit      int                `yaml:"limit"`
+		History    HistoryConfig      `yaml:"history"`
+		Pagination *PaginationConfig  `yaml:"pagination"`
+		// Update enables detecting the changes of the items already seen.
+		Update *UpdateConfig `yaml:"update"`
+		// LinkContent enables fetching the linked pages of the items concurrently.
+		LinkContent *LinkContentConfig `yaml:"linkContent"`
+	}
+	// HistoryConfig configures the previously seen items kept in the feed after they dropped off the source.
+	HistoryConfig struct {
+		// Items is the max number of the previously seen items.
+		Items int `yaml:"items"`
+		// Days is the number of days to keep the items since they were seen last.
+		Days int `yaml:"days"`
 	}
 	FeedConfig struct {
 		ID          tmpl.TemplateField `yaml:"id"`
@@ -50,6 +68,8 @@ type (
 		Content     tmpl.TemplateField `yaml:"content"`
 		Link        LinkConfig         `yaml:"link"`
 		Source      LinkConfig         `yaml:"source"`
+		Published   DateConfig         `yaml:"published"`
+		Updated     DateConfig         `yaml:"updated"`
 		Enclosure   struct {
 			URL    tmpl.TemplateField `yaml:"url"`
 			Length tmpl.TemplateField `yaml:"length"`
@@ -69,6 +89,12 @@ type (
 	TemplateFeedGenerator struct {
 		config *TemplateFeedGeneratorConfig
 	}
+	// document is a source document which can be split into the item contents.
+	document interface {
+		itemContents(selector string) ([]interface{}, error)
+		// link returns the URL selected by the selector, or empty string if not found.
+		link(selector string) string
+	}
 )
 
 func (g *TemplateFeedGenerator) LoadOptions(options config.GeneratorOptions) error {
@@ -76,6 +102,22 @@ func (g *TemplateFeedGenerator) LoadOptions(options config.GeneratorOptions) err
 	if err := options.Unmarshal(&c); err != nil {
 		return err
 	}
+	if c.Source == nil {
+		return errors.New("'source' is required")
+	}
+	if err := c.Source.Validate(); err != nil {
+		return err
+	}
+	if c.Pagination != nil {
+		if err := c.Pagination.validate(); err != nil {
+			return err
+		}
+	}
+	if c.LinkContent != nil {
+		if err := c.LinkContent.validate(); err != nil {
+			return err
+		}
+	}
 	g.config = &c
 	return nil
 }
@@ -93,29 +135,53 @@ func (g *TemplateFeedGenerator) Generate(context *generator.Context) (feed *feed
 
 func (g *TemplateFeedGenerator) generate(context *generator.Context) (*feeds.Feed, error) {
 	templateContext := context.TemplateContext
-	context.TemplateContext.AddFuncs(map[string]interface{}{
-		"ReplaceAll": func(old, new, s string) string {
-			return strings.ReplaceAll(s, old, new)
-		},
-		"Attr": func(attr string, input *Selection) string {
-			return input.Attr(attr)
-		},
-		"Text": func(input interface{}) string {
-			return toString(templateContext, input)
-		},
-	})
+	context.TemplateContext.AddFuncs(templateFuncs(templateContext))
 
 	/*
 	 * Source
 	 */
+	var request source.Request
 	var baseURL *url.URL
-	var doc *Selection
-	if u, d, err := g.loadSource(templateContext); err == nil {
+	var doc document
+	if r, u, d, err := g.loadSource(templateContext, context.Labels); err == nil {
+		request = r
 		baseURL = u
 		doc = d
 	} else {
 		return nil, err
 	}
+	return g.generateFromDocument(context, request, baseURL, doc)
+}
+
+// ParseHTML parses the HTML document obtained by the caller for GenerateFromHTML.
+func ParseHTML(html string) (*Selection, error) {
+	return newSelectionFromReader(strings.NewReader(html))
+}
+
+// GenerateFromHTML generates the feed from the HTML document obtained by the caller, such as the DOM rendered by a browser.
+// The source of the config is not used, and .LinkContent and pagination are not available.
+func GenerateFromHTML(context *generator.Context, config *TemplateFeedGeneratorConfig, documentURL string, doc *Selection) (feed *feeds.Feed, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("failed to generate: %v", rec)
+			feed = nil
+		}
+	}()
+	g := &TemplateFeedGenerator{config: config}
+	templateContext := context.TemplateContext
+	templateContext.AddFuncs(templateFuncs(templateContext))
+	baseURL, err := url.Parse(documentURL)
+	if err != nil {
+		return nil, err
+	}
+	templateContext.Set("URL", baseURL.String())
+	templateContext.Set("Content", doc)
+	return g.generateFromDocument(context, nil, baseURL, doc)
+}
+
+// generateFromDocument generates the feed from the source document. request is nil if the document is not from the source.
+func (g *TemplateFeedGenerator) generateFromDocument(context *generator.Context, request source.Request, baseURL *url.URL, doc document) (*feeds.Feed, error) {
+	templateContext := context.TemplateContext
 
 	/*
 	 * Feed
@@ -131,58 +197,142 @@ func (g *TemplateFeedGenerator) generate(context *generator.Context) (*feeds.Fee
 	 * Items
 	 */
 	templateContext.Set("Item", g.config.Item)
-	itemContents, err := doc.List(g.config.List.MustEvaluate(templateContext))
+	itemContents, pageURLs, err := g.loadItemContents(templateContext, context.Repository, request, baseURL, doc)
 	if err != nil {
 		return nil, err
 	}
-	g.config.Item.Link.HREF.ResultMapper = func(link string) (string, error) {
-		link = strings.TrimSpace(link)
-		linkURL, err := url.Parse(link)
-		if err != nil {
-			return "", err
-		}
-		if !linkURL.IsAbs() {
-			link = baseURL.ResolveReference(linkURL).String()
-		}
-		return link, nil
+	if g.config.Limit > 0 && len(itemContents) > g.config.Limit {
+		itemContents = itemContents[:g.config.Limit]
+		pageURLs = pageURLs[:g.config.Limit]
 	}
+	linkContents := g.prefetchLinkContents(templateContext, context.Repository, request, itemContents, pageURLs)
 	itemTemplateContext := templateContext
 	for i, itemContent := range itemContents {
-		if g.config.Limit > 0 && i >= g.config.Limit {
-			break
-		}
-		templateContext = itemTemplateContext.Child()
-		if item, err := g.loadItem(templateContext, context.Repository, itemContent); err == nil {
+		templateContext = newItemContext(itemTemplateContext)
+		// .URL of the item is the URL of the page where the item is found
+		templateContext.Set("URL", pageURLs[i])
+		if item, err := g.loadItem(templateContext, context.Repository, context.FeedKey, context.Labels, request, itemContent, linkContents[i]); err == nil {
 			feed.Items = append(feed.Items, item)
 		} else {
 			return nil, err
 		}
 	}
+	if err := g.loadHistory(context.Repository, context.FeedKey, feed); err != nil {
+		return nil, err
+	}
+	// Derive the feed times from the items so that the feed doesn't change unless the items change.
+	for i, item := range feed.Items {
+		if i == 0 || item.Created.Before(feed.Created) {
+			feed.Created = item.Created
+		}
+		if i == 0 || item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+	}
 	return feed, nil
 }
 
-func (g *TemplateFeedGenerator) loadSource(context *tmpl.TemplateContext) (*url.URL, *Selection, error) {
-	if err := g.config.Source.HTTP.Init(context); err != nil {
-		return nil, nil, fmt.Errorf("failed to initialize source: %w", err)
+func (g *TemplateFeedGenerator) loadHistory(repository *repo.Repository, feedKey repo.Key, feed *feeds.Feed) error {
+	history := g.config.History
+	if history.Items <= 0 && history.Days <= 0 {
+		return nil
+	}
+	var since time.Time
+	if history.Days > 0 {
+		since = time.Now().AddDate(0, 0, -history.Days)
+	}
+	items, err := repository.Item.GetFeedItems(feedKey, since, history.Items)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+	current := make(map[string]struct{}, len(feed.Items))
+	for _, item := range feed.Items {
+		current[item.Id] = struct{}{}
+	}
+	for _, item := range items {
+		if _, exist := current[item.Id]; !exist {
+			feed.Items = append(feed.Items, item)
+		}
+	}
+	return nil
+}
+
+func templateFuncs(templateContext *tmpl.TemplateContext) map[string]interface{} {
+	return map[string]interface{}{
+		"ReplaceAll": func(old, new, s string) string {
+			return strings.ReplaceAll(s, old, new)
+		},
+		"Attr": func(attr string, input *Selection) string {
+			return input.Attr(attr)
+		},
+		"Text": textFunc(templateContext),
+	}
+}
+
+// textFunc returns the 'Text' func, which evaluates the template fields in the context.
+func textFunc(templateContext *tmpl.TemplateContext) func(input interface{}) string {
+	return func(input interface{}) string {
+		return toString(templateContext, input)
+	}
+}
+
+// newItemContext creates the context to evaluate an item.
+// 'Text' is bound to the item context so that the item fields passed to it can refer to .ItemContent and .LinkContent.
+func newItemContext(context *tmpl.TemplateContext) *tmpl.TemplateContext {
+	itemContext := context.Child()
+	itemContext.AddFuncs(map[string]interface{}{
+		"Text": textFunc(itemContext),
+	})
+	return itemContext
+}
+
+func (g *TemplateFeedGenerator) ValidationTarget() (interface{}, []string) {
+	return g.config, FuncNames()
+}
+
+// FuncNames returns the names of the template functions added on generation.
+func FuncNames() []string {
+	funcs := make([]string, 0)
+	for name := range templateFuncs(nil) {
+		funcs = append(funcs, name)
 	}
-	baseURLStr := g.config.Source.GetURL()
-	baseURL, err := url.Parse(baseURLStr)
+	return funcs
+}
+
+func (g *TemplateFeedGenerator) loadSource(context *tmpl.TemplateContext, labels metrics.Labels) (source.Request, *url.URL, document, error) {
+	request, err := g.config.Source.Init(context, labels)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize source: %w", err)
+	}
+	baseURL, err := url.Parse(request.GetURL())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	context.Set("URL", baseURL.String())
 
-	reader, err := g.config.Source.Open()
+	reader, err := request.Open()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	doc, err := newSelectionFromReader(reader)
+	defer reader.Close()
+	doc, err := g.parseDocument(reader)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	context.Set("Content", doc)
 
-	return baseURL, doc, nil
+	return request, baseURL, doc, nil
+}
+
+func (g *TemplateFeedGenerator) parseDocument(reader io.Reader) (document, error) {
+	switch format := g.config.Source.GetFormat(); format {
+	case source.FormatHTML:
+		return newSelectionFromReader(reader)
+	case source.FormatJSON:
+		return newJSONValueFromReader(reader)
+	default:
+		return nil, fmt.Errorf("unsupported source format: %s", format)
+	}
 }
 
 func (g *TemplateFeedGenerator) loadFeed(context *tmpl.TemplateContext, repository *repo.Repository) (*feeds.Feed, error) {
@@ -239,70 +389,159 @@ func (g *TemplateFeedGenerator) loadLink(context *tmpl.TemplateContext, link *Li
 	}
 }
 
-func (g *TemplateFeedGenerator) loadItem(context *tmpl.TemplateContext, repository *repo.Repository, itemContent *Selection) (*feeds.Item, error) {
+func (g *TemplateFeedGenerator) loadItem(context *tmpl.TemplateContext, repository *repo.Repository, feedKey repo.Key, labels metrics.Labels, request source.Request, itemContent interface{}, linkContent *Selection) (*feeds.Item, error) {
 	context.Set("ItemContent", itemContent)
-	context.Set("LinkContent", newSelectionFromFactory(func() (*goquery.Selection, error) {
-		if g.config.Item.Link.HREF.IsDefined() {
-			return loadDocument(g.config.Item.Link.HREF.MustEvaluate(context))
-		}
-		return nil, fmt.Errorf("'link' not defined in config file")
-	}))
+	if linkContent == nil {
+		linkContent = newSelectionFromFactory(func() (*goquery.Selection, error) {
+			if request == nil {
+				return nil, fmt.Errorf("'.LinkContent' not available without 'source'")
+			}
+			if g.config.Item.Link.HREF.IsDefined() {
+				return loadDocument(request, g.mustEvaluateItemLink(context))
+			}
+			return nil, fmt.Errorf("'link' not defined in config file")
+		})
+	}
+	context.Set("LinkContent", linkContent)
 
 	// Evaluate 'id' first for getting cache.
-	id := g.config.Item.ID.MustEvaluate(context)
-	if len(id) == 0 {
-		id = g.config.Item.Link.HREF.MustEvaluate(context)
-		if len(id) == 0 {
-			return nil, errors.New("'id' or 'link.href' is required")
-		}
+	id, err := g.itemID(context)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get cache or create new feed item
 	key := repo.IDKey(id)
-	if item, err := repository.Item.GetFeedItem(key); err == nil {
-		if item == nil {
-			item = new(feeds.Item)
-			item.Id = id
-			item.Title = g.config.Item.Title.MustEvaluate(context)
-			item.Description = g.config.Item.Description.MustEvaluate(context)
-			item.Author = g.loadAuthor(context, &g.config.Item.Author)
-			item.Content = g.config.Item.Content.MustEvaluate(context)
-			item.Link = g.loadLink(context, &g.config.Item.Link)
-			item.Source = g.loadLink(context, &g.config.Item.Source)
-			enclosureURL := g.config.Item.Enclosure.URL.MustEvaluate(context)
-			if len(enclosureURL) > 0 {
-				enclosureType := g.config.Item.Enclosure.Type.MustEvaluate(context)
-				enclosureLength := g.config.Item.Enclosure.Length.MustEvaluate(context)
-				if len(enclosureType) == 0 {
-					enclosureType = "false"
-				}
-				if len(enclosureLength) == 0 {
-					enclosureLength = "0"
-				}
-				item.Enclosure = &feeds.Enclosure{
-					Url:    enclosureURL,
-					Type:   enclosureType,
-					Length: enclosureLength,
-				}
-			}
+	cached, err := repository.Item.GetFeedItem(key)
+	if err != nil {
+		return nil, err
+	}
+	var item *feeds.Item
+	switch {
+	case cached == nil:
+		metrics.IncNewItems(labels)
+		if item, err = g.evaluateItem(context, id); err != nil {
+			return nil, err
+		}
+		// Fall back to the first seen time
+		if item.Created.IsZero() {
 			item.Created = time.Now()
+		}
+		if item.Updated.IsZero() {
 			item.Updated = item.Created
-			if err := repository.Item.PutFeedItem(key, item); err != nil {
-				return nil, err
-			}
 		}
-		return item, nil
-	} else {
+	case g.config.Update.enabled():
+		if item, err = g.updateItem(context, repository, key, cached); err != nil {
+			return nil, err
+		}
+	default:
+		item = cached
+	}
+	if err := repository.Item.PutFeedItem(feedKey, key, item); err != nil {
 		return nil, err
 	}
+	return item, nil
+}
+
+// evaluateItem evaluates the item fields. The times are zero if not defined.
+func (g *TemplateFeedGenerator) evaluateItem(context *tmpl.TemplateContext, id string) (*feeds.Item, error) {
+	item := new(feeds.Item)
+	item.Id = id
+	item.Title = g.config.Item.Title.MustEvaluate(context)
+	item.Description = g.config.Item.Description.MustEvaluate(context)
+	item.Author = g.loadAuthor(context, &g.config.Item.Author)
+	item.Content = g.config.Item.Content.MustEvaluate(context)
+	if href := g.mustEvaluateItemLink(context); len(href) > 0 {
+		item.Link = &feeds.Link{
+			Href:   href,
+			Length: g.config.Item.Link.Length.MustEvaluate(context),
+			Type:   g.config.Item.Link.Type.MustEvaluate(context),
+			Rel:    g.config.Item.Link.REL.MustEvaluate(context),
+		}
+	}
+	item.Source = g.loadLink(context, &g.config.Item.Source)
+	enclosureURL := g.config.Item.Enclosure.URL.MustEvaluate(context)
+	if len(enclosureURL) > 0 {
+		enclosureType := g.config.Item.Enclosure.Type.MustEvaluate(context)
+		enclosureLength := g.config.Item.Enclosure.Length.MustEvaluate(context)
+		if len(enclosureType) == 0 {
+			enclosureType = enclosureTypeOf(enclosureURL)
+		}
+		if len(enclosureLength) == 0 {
+			enclosureLength = "0"
+		}
+		item.Enclosure = &feeds.Enclosure{
+			Url:    enclosureURL,
+			Type:   enclosureType,
+			Length: enclosureLength,
+		}
+	}
+	// the times which cannot be parsed are left zero to fall back to the first seen time
+	if published, err := g.config.Item.Published.Evaluate(context); err == nil {
+		item.Created = published
+	} else if errors.Is(err, errUnparsableTime) {
+		log.Printf("Failed to parse 'item.published', fall back to the first seen time: id=%s, err=%s", id, err)
+	} else {
+		return nil, fmt.Errorf("failed to evaluate 'item.published': %w", err)
+	}
+	if updated, err := g.config.Item.Updated.Evaluate(context); err == nil {
+		item.Updated = updated
+	} else if errors.Is(err, errUnparsableTime) {
+		log.Printf("Failed to parse 'item.updated', fall back to the first seen time: id=%s, err=%s", id, err)
+	} else {
+		return nil, fmt.Errorf("failed to evaluate 'item.updated': %w", err)
+	}
+	return item, nil
+}
+
+// itemID evaluates the ID of the item in the context, which is 'id' or 'link.href' if 'id' is not defined.
+func (g *TemplateFeedGenerator) itemID(context *tmpl.TemplateContext) (string, error) {
+	id := g.config.Item.ID.MustEvaluate(context)
+	if len(id) == 0 {
+		id = g.mustEvaluateItemLink(context)
+		if len(id) == 0 {
+			return "", errors.New("'id' or 'link.href' is required")
+		}
+	}
+	return id, nil
 }
 
 func toString(templateContext *tmpl.TemplateContext, i interface{}) string {
 	switch v := i.(type) {
 	case *Selection:
 		return v.Text()
+	case *JSONValue:
+		return v.Text()
 	case tmpl.TemplateField:
 		return v.MustEvaluate(templateContext)
 	}
 	return fmt.Sprint(i)
 }
+
+// enclosureTypeOf guesses the MIME type of the enclosure by the extension of the URL.
+func enclosureTypeOf(enclosureURL string) string {
+	if u, err := url.Parse(enclosureURL); err == nil {
+		if t := mime.TypeByExtension(path.Ext(u.Path)); len(t) > 0 {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
+
+// mustEvaluateItemLink evaluates 'item.link.href', and resolves the relative link against .URL, the URL of the page where the item is found.
+func (g *TemplateFeedGenerator) mustEvaluateItemLink(context *tmpl.TemplateContext) string {
+	link := strings.TrimSpace(g.config.Item.Link.HREF.MustEvaluate(context))
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		panic(err)
+	}
+	if linkURL.IsAbs() {
+		return link
+	}
+	pageURL, _ := context.Get("URL").(string)
+	baseURL, err := url.Parse(pageURL)
+	if err != nil {
+		panic(err)
+	}
+	return baseURL.ResolveReference(linkURL).String()
+}
@@ -0,0 +1,57 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/uphy/feedgen/generator"
+	"github.com/uphy/feedgen/repo"
+	tmpl "github.com/uphy/feedgen/template"
+	"gopkg.in/yaml.v2"
+)
+
+func TestGenerateFromHTMLText(t *testing.T) {
+	var config TemplateFeedGeneratorConfig
+	if err := yaml.Unmarshal([]byte(`
+feed:
+  title: Test
+list: li
+item:
+  id: '{{ .Item.Link.HREF | Text }}'
+  title: '{{ .ItemContent.Select "a" }}'
+  content: '<img src="{{ .Item.Link.HREF | Text }}">'
+  link:
+    href: '{{ .ItemContent.Select "a" | Attr "href" }}'
+`), &config); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseHTML(`<ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li></ul>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := &generator.Context{
+		Repository:      repo.NewMemoryRepository(),
+		TemplateContext: tmpl.NewRootTemplateContext(),
+		FeedKey:         repo.IDKey("test"),
+	}
+	feed, err := GenerateFromHTML(context, &config, "http://example.com/list", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id      string
+		link    string
+		content string
+	}{
+		{id: "/a", link: "http://example.com/a", content: `<img src="/a">`},
+		{id: "/b", link: "http://example.com/b", content: `<img src="/b">`},
+	}
+	if len(feed.Items) != len(tests) {
+		t.Fatalf("len(feed.Items) = %d, want %d", len(feed.Items), len(tests))
+	}
+	for i, tt := range tests {
+		item := feed.Items[i]
+		if item.Id != tt.id || item.Link.Href != tt.link || item.Content != tt.content {
+			t.Errorf("feed.Items[%d] = {%s %s %s}, want {%s %s %s}", i, item.Id, item.Link.Href, item.Content, tt.id, tt.link, tt.content)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/repo"
+	tmpl "github.com/uphy/feedgen/template"
+)
+
+type (
+	// UpdateConfig configures re-evaluating the items already seen to detect their changes.
+	// `update: true` enables it without revisions.
+	UpdateConfig struct {
+		Enabled bool `yaml:"enabled"`
+		// Revisions is the max number of the previous versions of an item to keep in the repository.
+		Revisions int `yaml:"revisions"`
+	}
+	updateConfigYAML struct {
+		Enabled   *bool `yaml:"enabled"`
+		Revisions int   `yaml:"revisions"`
+	}
+)
+
+func (c *UpdateConfig) enabled() bool {
+	return c != nil && c.Enabled
+}
+
+func (c *UpdateConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var b bool
+	if err := unmarshal(&b); err == nil {
+		c.Enabled = b
+		return nil
+	}
+	var y updateConfigYAML
+	if err := unmarshal(&y); err != nil {
+		return err
+	}
+	// enabled unless explicitly disabled
+	c.Enabled = y.Enabled == nil || *y.Enabled
+	c.Revisions = y.Revisions
+	if c.Revisions < 0 {
+		return fmt.Errorf("'update.revisions' must be a non-negative integer: %d", c.Revisions)
+	}
+	return nil
+}
+
+// updateItem re-evaluates the cached item and returns the new version if the content is changed, otherwise the cached item.
+func (g *TemplateFeedGenerator) updateItem(context *tmpl.TemplateContext, repository *repo.Repository, key repo.Key, cached *feeds.Item) (*feeds.Item, error) {
+	item, err := g.evaluateItem(context, cached.Id)
+	if err != nil {
+		return nil, err
+	}
+	if itemHash(item) == itemHash(cached) {
+		return cached, nil
+	}
+
+	item.Created = cached.Created
+	if item.Updated.IsZero() || !item.Updated.After(cached.Updated) {
+		item.Updated = time.Now()
+	}
+	if g.config.Update.Revisions > 0 {
+		if err := g.putRevision(repository, key, cached); err != nil {
+			return nil, fmt.Errorf("failed to store revision: %w", err)
+		}
+	}
+	return item, nil
+}
+
+// putRevision stores the previous version of the item as an item of the revision feed, newest first.
+func (g *TemplateFeedGenerator) putRevision(repository *repo.Repository, key repo.Key, item *feeds.Item) error {
+	revisionKey := repo.GeneratedKey(key.Key(), "revisions")
+	revisions, err := repository.Feed.GetFeed(revisionKey)
+	if err != nil {
+		return err
+	}
+	if revisions == nil {
+		revisions = &feeds.Feed{Id: item.Id}
+	}
+	revisions.Items = append([]*feeds.Item{item}, revisions.Items...)
+	if len(revisions.Items) > g.config.Update.Revisions {
+		revisions.Items = revisions.Items[:g.config.Update.Revisions]
+	}
+	revisions.Updated = time.Now()
+	return repository.Feed.PutFeed(revisionKey, revisions)
+}
+
+// itemHash returns the hash of the item content. The times are excluded because they may be relative to now.
+func itemHash(item *feeds.Item) string {
+	fields := []string{item.Title, item.Description, item.Content}
+	if item.Author != nil {
+		fields = append(fields, item.Author.Name, item.Author.Email)
+	} else {
+		fields = append(fields, "", "")
+	}
+	for _, link := range []*feeds.Link{item.Link, item.Source} {
+		if link != nil {
+			fields = append(fields, link.Href)
+		} else {
+			fields = append(fields, "")
+		}
+	}
+	if item.Enclosure != nil {
+		fields = append(fields, item.Enclosure.Url, item.Enclosure.Type, item.Enclosure.Length)
+	}
+	hash := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/uphy/feedgen/config"
+	"github.com/uphy/feedgen/template"
+)
+
+type (
+	// ValidatableFeedGenerator is a FeedGenerator whose templates can be validated without generating a feed.
+	ValidatableFeedGenerator interface {
+		FeedGenerator
+		// ValidationTarget returns the loaded config and the names of the template functions the generator adds on generation.
+		ValidationTarget() (config interface{}, funcs []string)
+	}
+
+	// ValidationError is a problem found in a config file.
+	ValidationError struct {
+		File      string
+		Generator string
+		Field     string
+		Err       error
+	}
+)
+
+// contextFuncNames is the names of the template functions added by FeedGenerators.Generate.
+var contextFuncNames = []string{"Param", "QueryParam", "QueryParams"}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, 4)
+	for _, s := range []string{e.File, e.Generator, e.Field} {
+		if len(s) > 0 {
+			parts = append(parts, s)
+		}
+	}
+	parts = append(parts, e.Err.Error())
+	return strings.Join(parts, ": ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate validates the config and all the included generator configs, and returns all the problems found.
+// The config should be parsed by config.ParseConfigStrict to reject unknown keys.
+func (f *FeedGenerators) Validate(file string, c *config.Config) []*ValidationError {
+	errs := make([]*ValidationError, 0)
+	// deps is the dependencies of the generators to check after all the generators are known
+	deps := make(map[string][]string)
+	// locations is the file and the generator path of the generators to report the dependency errors
+	locations := make(map[string]ValidationError)
+	for i, name := range c.Include {
+		includedFile := path.Join("generator", "config", name+".yml")
+		b, err := predefinedGeneratorConfigs.ReadFile(path.Join("config", name+".yml"))
+		if err != nil {
+			errs = append(errs, &ValidationError{file, "", fmt.Sprintf("include[%d]", i), fmt.Errorf("predefined config not found: %s", name)})
+			continue
+		}
+		deps[name] = nil
+		locations[name] = ValidationError{File: includedFile, Generator: name}
+		generatorConfig, err := config.ParseGeneratorConfigStrict(b)
+		if err != nil {
+			errs = append(errs, &ValidationError{includedFile, name, "", err})
+			continue
+		}
+		errs = append(errs, f.validateGeneratorConfig(includedFile, name, name, generatorConfig, deps)...)
+	}
+
+	names := make([]string, 0, len(c.Generators))
+	for name := range c.Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		generatorConfig := c.Generators[name]
+		deps[name] = nil
+		locations[name] = ValidationError{File: file, Generator: "generators." + name}
+		if generatorConfig == nil {
+			errs = append(errs, &ValidationError{file, "generators." + name, "", fmt.Errorf("empty generator config")})
+			continue
+		}
+		errs = append(errs, f.validateGeneratorConfig(file, "generators."+name, name, generatorConfig, deps)...)
+	}
+
+	depNames := make([]string, 0, len(deps))
+	for name := range deps {
+		depNames = append(depNames, name)
+	}
+	sort.Strings(depNames)
+	for _, name := range depNames {
+		if err := dependencyError(name, deps); err != nil {
+			e := locations[name]
+			e.Err = err
+			errs = append(errs, &e)
+		}
+	}
+	return errs
+}
+
+// validateGeneratorConfig validates the generator config, and records the dependencies of the generator to deps.
+func (f *FeedGenerators) validateGeneratorConfig(file string, name string, generatorName string, c *config.GeneratorConfig, deps map[string][]string) []*ValidationError {
+	errs := make([]*ValidationError, 0)
+	newError := func(field string, err error) {
+		errs = append(errs, &ValidationError{file, name, field, err})
+	}
+
+	gen, err := f.newGenerator(c)
+	if err != nil {
+		newError("", err)
+		// continue validating the templates ignoring the unknown keys
+		lenient := *c
+		lenient.Options = c.Options.WithStrict(false)
+		if gen, err = f.newGenerator(&lenient); err != nil {
+			return errs
+		}
+	}
+	deps[generatorName] = dependencies(gen)
+	if c.Filters != nil {
+		if _, err := newItemFilter(c.Filters); err != nil {
+			newError("filters", err)
+		}
+	}
+
+	if _, err := c.Endpoint.Check(nil); err != nil {
+		newError("endpoint", err)
+		return errs
+	}
+	endpoint, err := c.Endpoint.Evaluate(f.templateContext)
+	if err != nil {
+		newError("endpoint", err)
+		return errs
+	}
+	endpointParameters := make(map[string]struct{})
+	for _, p := range parseEndpointParameters(endpoint) {
+		endpointParameters[p] = struct{}{}
+	}
+	if c.Schedule != nil {
+		for i, p := range c.Schedule.Parameters {
+			for k := range p.Parameters {
+				if _, exist := endpointParameters[k]; !exist {
+					newError(fmt.Sprintf("schedule.parameters[%d].parameters.%s", i, k), fmt.Errorf("parameter not defined in endpoint: endpoint=%s", endpoint))
+				}
+			}
+		}
+	}
+
+	v, ok := gen.(ValidatableFeedGenerator)
+	if !ok {
+		return errs
+	}
+	target, generatorFuncs := v.ValidationTarget()
+	template.WalkTemplateFields(target, func(path string, field template.TemplateField) {
+		info, err := field.Check(append(append([]string{}, contextFuncNames...), generatorFuncs...))
+		if err != nil {
+			newError(path, err)
+			return
+		}
+		for _, p := range info.FuncArgs("Param") {
+			if _, exist := endpointParameters[p]; !exist {
+				newError(path, fmt.Errorf("parameter %q not defined in endpoint: endpoint=%s", p, endpoint))
+			}
+		}
+	})
+	return errs
+}
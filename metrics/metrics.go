@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// Labels identifies the generator which the metrics are observed in.
+	Labels struct {
+		Generator string
+		Type      string
+	}
+)
+
+const namespace = "feedgen"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	generations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "generations_total",
+		Help:      "Number of the feed generations.",
+	}, []string{"generator", "type"})
+	generationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "generation_errors_total",
+		Help:      "Number of the failed feed generations.",
+	}, []string{"generator", "type"})
+	generationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "generation_duration_seconds",
+		Help:      "Duration of the feed generations.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"generator", "type"})
+	itemsEmitted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_emitted_total",
+		Help:      "Number of the items in the generated feeds.",
+	}, []string{"generator", "type"})
+	newItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "new_items_total",
+		Help:      "Number of the items not found in the repository.",
+	}, []string{"generator", "type"})
+	sourceFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "source_fetch_duration_seconds",
+		Help:      "Duration of the HTTP source fetches.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"generator", "type", "code"})
+	browserActionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "browser_action_duration_seconds",
+		Help:      "Duration of the browser actions.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"generator", "type", "action"})
+	repositoryOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "Duration of the repository operations.",
+		Buckets:   []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		generations,
+		generationErrors,
+		generationDuration,
+		itemsEmitted,
+		newItems,
+		sourceFetchDuration,
+		browserActionDuration,
+		repositoryOperationDuration,
+	)
+}
+
+// Handler returns the HTTP handler exposing the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveGeneration records a feed generation.
+func ObserveGeneration(labels Labels, duration time.Duration, items int, err error) {
+	generations.WithLabelValues(labels.Generator, labels.Type).Inc()
+	generationDuration.WithLabelValues(labels.Generator, labels.Type).Observe(duration.Seconds())
+	if err != nil {
+		generationErrors.WithLabelValues(labels.Generator, labels.Type).Inc()
+		return
+	}
+	itemsEmitted.WithLabelValues(labels.Generator, labels.Type).Add(float64(items))
+}
+
+// IncNewItems records an item not found in the repository.
+func IncNewItems(labels Labels) {
+	newItems.WithLabelValues(labels.Generator, labels.Type).Inc()
+}
+
+// ObserveSourceFetch records an HTTP source fetch. statusCode is 0 if no response is received.
+func ObserveSourceFetch(labels Labels, duration time.Duration, statusCode int) {
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	sourceFetchDuration.WithLabelValues(labels.Generator, labels.Type, code).Observe(duration.Seconds())
+}
+
+// ObserveBrowserAction records a browser action.
+func ObserveBrowserAction(labels Labels, action string, duration time.Duration) {
+	browserActionDuration.WithLabelValues(labels.Generator, labels.Type, action).Observe(duration.Seconds())
+}
+
+// ObserveRepositoryOperation records a repository operation.
+func ObserveRepositoryOperation(operation string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	repositoryOperationDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
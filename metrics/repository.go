@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/uphy/feedgen/repo"
+)
+
+type (
+	// instrumentedRepository observes the latency of the operations of the repository.
+	instrumentedRepository struct {
+		repository *repo.Repository
+	}
+)
+
+// InstrumentRepository returns the repository which observes the latency of the operations.
+func InstrumentRepository(repository *repo.Repository) *repo.Repository {
+	r := &instrumentedRepository{repository}
+	return &repo.Repository{Feed: r, Item: r, Response: r, Media: r}
+}
+
+func (r *instrumentedRepository) PutFeed(key repo.Key, feed *feeds.Feed) error {
+	start := time.Now()
+	err := r.repository.Feed.PutFeed(key, feed)
+	ObserveRepositoryOperation("put_feed", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetFeed(key repo.Key) (*feeds.Feed, error) {
+	start := time.Now()
+	feed, err := r.repository.Feed.GetFeed(key)
+	ObserveRepositoryOperation("get_feed", time.Since(start), err)
+	return feed, err
+}
+
+func (r *instrumentedRepository) PutFeedItem(feed repo.Key, key repo.Key, item *feeds.Item) error {
+	start := time.Now()
+	err := r.repository.Item.PutFeedItem(feed, key, item)
+	ObserveRepositoryOperation("put_feed_item", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetFeedItem(key repo.Key) (*feeds.Item, error) {
+	start := time.Now()
+	item, err := r.repository.Item.GetFeedItem(key)
+	ObserveRepositoryOperation("get_feed_item", time.Since(start), err)
+	return item, err
+}
+
+func (r *instrumentedRepository) GetFeedItems(feed repo.Key, since time.Time, limit int) ([]*feeds.Item, error) {
+	start := time.Now()
+	items, err := r.repository.Item.GetFeedItems(feed, since, limit)
+	ObserveRepositoryOperation("get_feed_items", time.Since(start), err)
+	return items, err
+}
+
+func (r *instrumentedRepository) PutResponse(key repo.Key, response *repo.Response) error {
+	start := time.Now()
+	err := r.repository.Response.PutResponse(key, response)
+	ObserveRepositoryOperation("put_response", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetResponse(key repo.Key) (*repo.Response, error) {
+	start := time.Now()
+	response, err := r.repository.Response.GetResponse(key)
+	ObserveRepositoryOperation("get_response", time.Since(start), err)
+	return response, err
+}
+
+func (r *instrumentedRepository) PutMedia(key repo.Key, media *repo.Media) error {
+	start := time.Now()
+	err := r.repository.Media.PutMedia(key, media)
+	ObserveRepositoryOperation("put_media", time.Since(start), err)
+	return err
+}
+
+func (r *instrumentedRepository) GetMedia(key repo.Key) (*repo.Media, error) {
+	start := time.Now()
+	media, err := r.repository.Media.GetMedia(key)
+	ObserveRepositoryOperation("get_media", time.Since(start), err)
+	return media, err
+}
+
+func (r *instrumentedRepository) Close() error {
+	return r.repository.Close()
+}
@@ -23,7 +23,7 @@ func NewBadgerRepository(file string) (*Repository, error) {
 		return nil, err
 	}
 	r := &BadgerRepository{db}
-	return &Repository{r, r}, nil
+	return &Repository{r, r, r, r}, nil
 }
 
 func (r *BadgerRepository) PutFeed(key Key, feed *feeds.Feed) error {
@@ -41,8 +41,54 @@ func (r *BadgerRepository) GetFeed(key Key) (*feeds.Feed, error) {
 	return &feed, nil
 }
 
-func (r *BadgerRepository) PutFeedItem(key Key, item *feeds.Item) error {
-	return r.put("i", key, item)
+func (r *BadgerRepository) PutFeedItem(feed Key, key Key, item *feeds.Item) error {
+	if err := r.put("i", key, item); err != nil {
+		return err
+	}
+	indexKey := feedItemIndexKey(feed, key)
+	index := feedItemIndex{Key: key.Key()}
+	if err := r.get("fi", indexKey, &index); err != nil && err != badger.ErrKeyNotFound {
+		return err
+	}
+	now := time.Now()
+	if index.FirstSeen.IsZero() {
+		index.FirstSeen = now
+	}
+	index.LastSeen = now
+	return r.put("fi", indexKey, &index)
+}
+
+func (r *BadgerRepository) GetFeedItems(feed Key, since time.Time, limit int) ([]*feeds.Item, error) {
+	indexes := make([]*feedItemIndex, 0)
+	if err := r.db.View(func(txn *badger.Txn) error {
+		prefix := r.key("fi", feedItemIndexPrefix(feed))
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var index feedItemIndex
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &index)
+			}); err != nil {
+				return err
+			}
+			indexes = append(indexes, &index)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	items := make([]*feeds.Item, 0)
+	for _, index := range selectFeedItemIndexes(indexes, since, limit) {
+		item, err := r.GetFeedItem(IDKey(index.Key))
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
 }
 
 func (r *BadgerRepository) GetFeedItem(key Key) (*feeds.Item, error) {
@@ -56,6 +102,36 @@ func (r *BadgerRepository) GetFeedItem(key Key) (*feeds.Item, error) {
 	return &item, nil
 }
 
+func (r *BadgerRepository) PutResponse(key Key, response *Response) error {
+	return r.put("r", key, response)
+}
+
+func (r *BadgerRepository) GetResponse(key Key) (*Response, error) {
+	var response Response
+	if err := r.get("r", key, &response); err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (r *BadgerRepository) PutMedia(key Key, media *Media) error {
+	return r.put("m", key, media)
+}
+
+func (r *BadgerRepository) GetMedia(key Key) (*Media, error) {
+	var media Media
+	if err := r.get("m", key, &media); err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
 func (r *BadgerRepository) get(prefix string, key Key, v interface{}) error {
 	var b []byte
 	if err := r.db.View(func(txn *badger.Txn) error {
@@ -84,7 +160,7 @@ func (r *BadgerRepository) put(prefix string, key Key, v interface{}) error {
 	}
 	return r.db.Update(func(txn *badger.Txn) error {
 		entry := badger.NewEntry(r.key(prefix, key), b)
-		entry.WithTTL(time.Hour * 24 * 30) // 30 days
+		entry.WithTTL(Retention)
 		return txn.SetEntry(entry)
 	})
 }
@@ -2,6 +2,9 @@ package repo
 
 import (
 	"encoding/json"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/gorilla/feeds"
@@ -10,12 +13,13 @@ import (
 type (
 	MemoryRepository struct {
 		keyValue map[string][]byte
+		mutex    sync.RWMutex
 	}
 )
 
 func NewMemoryRepository() *Repository {
-	r := &MemoryRepository{make(map[string][]byte)}
-	return &Repository{r, r}
+	r := &MemoryRepository{keyValue: make(map[string][]byte)}
+	return &Repository{r, r, r, r}
 }
 
 func (r *MemoryRepository) PutFeed(key Key, feed *feeds.Feed) error {
@@ -33,8 +37,51 @@ func (r *MemoryRepository) GetFeed(key Key) (*feeds.Feed, error) {
 	return &feed, nil
 }
 
-func (r *MemoryRepository) PutFeedItem(key Key, item *feeds.Item) error {
-	return r.put("i", key, item)
+func (r *MemoryRepository) PutFeedItem(feed Key, key Key, item *feeds.Item) error {
+	if err := r.put("i", key, item); err != nil {
+		return err
+	}
+	indexKey := feedItemIndexKey(feed, key)
+	index := feedItemIndex{Key: key.Key()}
+	if err := r.get("fi", indexKey, &index); err != nil && err != badger.ErrKeyNotFound {
+		return err
+	}
+	now := time.Now()
+	if index.FirstSeen.IsZero() {
+		index.FirstSeen = now
+	}
+	index.LastSeen = now
+	return r.put("fi", indexKey, &index)
+}
+
+func (r *MemoryRepository) GetFeedItems(feed Key, since time.Time, limit int) ([]*feeds.Item, error) {
+	prefix := r.key("fi", feedItemIndexPrefix(feed))
+	indexes := make([]*feedItemIndex, 0)
+	r.mutex.RLock()
+	for k, v := range r.keyValue {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		var index feedItemIndex
+		if err := json.Unmarshal(v, &index); err != nil {
+			r.mutex.RUnlock()
+			return nil, err
+		}
+		indexes = append(indexes, &index)
+	}
+	r.mutex.RUnlock()
+
+	items := make([]*feeds.Item, 0)
+	for _, index := range selectFeedItemIndexes(indexes, since, limit) {
+		item, err := r.GetFeedItem(IDKey(index.Key))
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
 }
 
 func (r *MemoryRepository) GetFeedItem(key Key) (*feeds.Item, error) {
@@ -48,7 +95,39 @@ func (r *MemoryRepository) GetFeedItem(key Key) (*feeds.Item, error) {
 	return &item, nil
 }
 
+func (r *MemoryRepository) PutResponse(key Key, response *Response) error {
+	return r.put("r", key, response)
+}
+
+func (r *MemoryRepository) GetResponse(key Key) (*Response, error) {
+	var response Response
+	if err := r.get("r", key, &response); err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (r *MemoryRepository) PutMedia(key Key, media *Media) error {
+	return r.put("m", key, media)
+}
+
+func (r *MemoryRepository) GetMedia(key Key) (*Media, error) {
+	var media Media
+	if err := r.get("m", key, &media); err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
 func (r *MemoryRepository) get(prefix string, key Key, v interface{}) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	if value, exist := r.keyValue[r.key(prefix, key)]; exist {
 		return json.Unmarshal(value, v)
 	} else {
@@ -61,6 +140,8 @@ func (r *MemoryRepository) put(prefix string, key Key, v interface{}) error {
 	if err != nil {
 		return err
 	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	r.keyValue[r.key(prefix, key)] = b
 	return nil
 }
@@ -70,6 +151,8 @@ func (r *MemoryRepository) key(prefix string, key Key) string {
 }
 
 func (r *MemoryRepository) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	r.keyValue = make(map[string][]byte)
 	return nil
 }
@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gorilla/feeds"
 )
@@ -17,20 +19,57 @@ type (
 	}
 	FeedItemRepository interface {
 		io.Closer
-		PutFeedItem(Key, *feeds.Item) error
+		// PutFeedItem stores the item seen in the feed.
+		PutFeedItem(feed Key, key Key, item *feeds.Item) error
 		GetFeedItem(Key) (*feeds.Item, error)
+		// GetFeedItems returns the items seen in the feed since the time, newest first.
+		// Zero since or limit means no restriction.
+		GetFeedItems(feed Key, since time.Time, limit int) ([]*feeds.Item, error)
+	}
+	ResponseRepository interface {
+		io.Closer
+		PutResponse(Key, *Response) error
+		GetResponse(Key) (*Response, error)
+	}
+	MediaRepository interface {
+		io.Closer
+		PutMedia(Key, *Media) error
+		GetMedia(Key) (*Media, error)
 	}
 	Repository struct {
-		Feed FeedRepository
-		Item FeedItemRepository
+		Feed     FeedRepository
+		Item     FeedItemRepository
+		Response ResponseRepository
+		Media    MediaRepository
+	}
+	// Response is a rendered feed response.
+	Response struct {
+		ContentType  string    `json:"contentType"`
+		Body         string    `json:"body"`
+		LastModified time.Time `json:"lastModified"`
+		GeneratedAt  time.Time `json:"generatedAt"`
+	}
+	// Media is a binary content such as a screenshot referred from the feeds.
+	Media struct {
+		ContentType string `json:"contentType"`
+		Data        []byte `json:"data"`
 	}
 	Key interface {
 		Key() string
 	}
 	idKey        string
 	generatedKey []string
+	// feedItemIndex records when the item was seen in the feed.
+	feedItemIndex struct {
+		Key       string    `json:"key"`
+		FirstSeen time.Time `json:"firstSeen"`
+		LastSeen  time.Time `json:"lastSeen"`
+	}
 )
 
+// Retention is the period to keep the data not stored again, such as the items not seen in the feeds.
+const Retention = time.Hour * 24 * 30
+
 func IDKey(id string) Key {
 	return idKey(id)
 }
@@ -49,11 +88,47 @@ func (k generatedKey) Key() string {
 	return fmt.Sprintf("%x", b)
 }
 
+func feedItemIndexPrefix(feed Key) Key {
+	return IDKey(GeneratedKey(feed.Key()).Key() + ":")
+}
+
+func feedItemIndexKey(feed Key, item Key) Key {
+	return IDKey(feedItemIndexPrefix(feed).Key() + item.Key())
+}
+
 func (r *Repository) Close() error {
-	feedErr := r.Feed.Close()
-	itemErr := r.Item.Close()
-	if feedErr != nil || itemErr != nil {
-		return fmt.Errorf("failed to close: feedErr=%w, itemErr%w", feedErr, itemErr)
+	// the repositories are usually implemented by the same instance
+	closed := make(map[io.Closer]struct{})
+	errs := make([]string, 0)
+	for _, c := range []io.Closer{r.Feed, r.Item, r.Response, r.Media} {
+		if _, exist := closed[c]; exist {
+			continue
+		}
+		closed[c] = struct{}{}
+		if err := c.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close: %s", strings.Join(errs, ", "))
 	}
 	return nil
 }
+
+// selectFeedItemIndexes filters the indexes seen since the time and sorts them by first seen time, newest first.
+func selectFeedItemIndexes(indexes []*feedItemIndex, since time.Time, limit int) []*feedItemIndex {
+	selected := make([]*feedItemIndex, 0, len(indexes))
+	for _, index := range indexes {
+		if index.LastSeen.Before(since) {
+			continue
+		}
+		selected = append(selected, index)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].FirstSeen.After(selected[j].FirstSeen)
+	})
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
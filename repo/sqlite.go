@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/feeds"
+	_ "modernc.org/sqlite"
+)
+
+type (
+	SQLiteRepository struct {
+		db *sql.DB
+	}
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS feeds (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen  DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen  DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS feed_items (
+	feed_key   TEXT NOT NULL,
+	item_key   TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen  DATETIME NOT NULL,
+	PRIMARY KEY (feed_key, item_key)
+);
+CREATE TABLE IF NOT EXISTS responses (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen  DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS media (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen  DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS feed_items_last_seen ON feed_items (feed_key, last_seen);
+`
+
+func NewSQLiteRepository(file string) (*Repository, error) {
+	db, err := sql.Open("sqlite", file)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows only one writer at a time.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	r := &SQLiteRepository{db}
+	if err := r.purge(time.Now().UTC().Add(-Retention)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Repository{r, r, r, r}, nil
+}
+
+func (r *SQLiteRepository) PutFeed(key Key, feed *feeds.Feed) error {
+	return r.put("feeds", key, feed)
+}
+
+func (r *SQLiteRepository) GetFeed(key Key) (*feeds.Feed, error) {
+	var feed feeds.Feed
+	if err := r.get("feeds", key, &feed); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *SQLiteRepository) PutFeedItem(feed Key, key Key, item *feeds.Item) error {
+	if err := r.put("items", key, item); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err := r.db.Exec(`INSERT INTO feed_items (feed_key, item_key, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (feed_key, item_key) DO UPDATE SET last_seen = excluded.last_seen`,
+		feed.Key(), key.Key(), now, now)
+	return err
+}
+
+func (r *SQLiteRepository) GetFeedItem(key Key) (*feeds.Item, error) {
+	var item feeds.Item
+	if err := r.get("items", key, &item); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *SQLiteRepository) GetFeedItems(feed Key, since time.Time, limit int) ([]*feeds.Item, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.Query(`SELECT i.value FROM feed_items fi JOIN items i ON i.key = fi.item_key
+		WHERE fi.feed_key = ? AND fi.last_seen >= ? ORDER BY fi.first_seen DESC LIMIT ?`,
+		feed.Key(), since.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*feeds.Item, 0)
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var item feeds.Item
+		if err := json.Unmarshal([]byte(b), &item); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+func (r *SQLiteRepository) PutResponse(key Key, response *Response) error {
+	return r.put("responses", key, response)
+}
+
+func (r *SQLiteRepository) GetResponse(key Key) (*Response, error) {
+	var response Response
+	if err := r.get("responses", key, &response); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (r *SQLiteRepository) PutMedia(key Key, media *Media) error {
+	return r.put("media", key, media)
+}
+
+func (r *SQLiteRepository) GetMedia(key Key) (*Media, error) {
+	var media Media
+	if err := r.get("media", key, &media); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// get and put take a table name which is a constant, never a user input.
+func (r *SQLiteRepository) get(table string, key Key, v interface{}) error {
+	var b string
+	if err := r.db.QueryRow("SELECT value FROM "+table+" WHERE key = ?", key.Key()).Scan(&b); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(b), v)
+}
+
+func (r *SQLiteRepository) put(table string, key Key, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = r.db.Exec("INSERT INTO "+table+" (key, value, first_seen, last_seen) VALUES (?, ?, ?, ?)"+
+		" ON CONFLICT (key) DO UPDATE SET value = excluded.value, last_seen = excluded.last_seen",
+		key.Key(), string(b), now, now)
+	return err
+}
+
+func (r *SQLiteRepository) purge(before time.Time) error {
+	for _, table := range []string{"feeds", "items", "feed_items", "responses", "media"} {
+		if _, err := r.db.Exec("DELETE FROM "+table+" WHERE last_seen < ?", before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
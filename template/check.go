@@ -0,0 +1,214 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template/parse"
+)
+
+type (
+	// TemplateInfo is the static information of a template.
+	TemplateInfo struct {
+		FuncCalls []FuncCall
+		// Fields is the names of the top-level fields referred such as 'URL' of '.URL.Path'.
+		Fields []string
+	}
+	// FuncCall is a function call in a template with its string literal arguments.
+	FuncCall struct {
+		Name string
+		Args []string
+	}
+)
+
+// FuncArgs returns the first string literal arguments of the calls of the function without duplicates.
+func (i *TemplateInfo) FuncArgs(name string) []string {
+	args := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, call := range i.FuncCalls {
+		if call.Name != name || len(call.Args) == 0 {
+			continue
+		}
+		if _, exist := seen[call.Args[0]]; exist {
+			continue
+		}
+		seen[call.Args[0]] = struct{}{}
+		args = append(args, call.Args[0])
+	}
+	return args
+}
+
+// HasFuncCall returns true if the function is called in the template.
+func (i *TemplateInfo) HasFuncCall(name string) bool {
+	for _, call := range i.FuncCalls {
+		if call.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasField returns true if the top-level field is referred in the template.
+func (i *TemplateInfo) HasField(name string) bool {
+	for _, field := range i.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+var builtinFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or",
+	"print", "printf", "println", "urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// Analyze parses the template without evaluating it and collects the function calls.
+func (t TemplateField) Analyze() (*TemplateInfo, error) {
+	tree := parse.New("template-string")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(t.template, "", "", make(map[string]*parse.Tree)); err != nil {
+		return nil, fmt.Errorf("cannot parse template: %w", err)
+	}
+
+	info := &TemplateInfo{}
+	collectFuncCalls(tree.Root, info)
+	return info, nil
+}
+
+// Check parses the template without evaluating it and checks the called functions.
+// The names of the functions added to the context on evaluation are given as contextFuncs.
+func (t TemplateField) Check(contextFuncs []string) (*TemplateInfo, error) {
+	info, err := t.Analyze()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]struct{})
+	for _, name := range builtinFuncs {
+		known[name] = struct{}{}
+	}
+	for name := range globalFuncs {
+		known[name] = struct{}{}
+	}
+	for _, name := range contextFuncs {
+		known[name] = struct{}{}
+	}
+	for _, call := range info.FuncCalls {
+		if _, exist := known[call.Name]; !exist {
+			return nil, fmt.Errorf("function %q not defined", call.Name)
+		}
+	}
+	return info, nil
+}
+
+func collectFuncCalls(node parse.Node, info *TemplateInfo) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			collectFuncCalls(child, info)
+		}
+	case *parse.ActionNode:
+		collectFuncCalls(n.Pipe, info)
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			collectFuncCalls(cmd, info)
+		}
+	case *parse.CommandNode:
+		args := n.Args
+		if len(args) > 0 {
+			if ident, ok := args[0].(*parse.IdentifierNode); ok {
+				call := FuncCall{Name: ident.Ident}
+				for _, arg := range args[1:] {
+					if s, ok := arg.(*parse.StringNode); ok {
+						call.Args = append(call.Args, s.Text)
+					}
+				}
+				info.FuncCalls = append(info.FuncCalls, call)
+				args = args[1:]
+			}
+		}
+		for _, arg := range args {
+			collectFuncCalls(arg, info)
+		}
+	case *parse.IdentifierNode:
+		// function called without arguments as an argument of another function
+		info.FuncCalls = append(info.FuncCalls, FuncCall{Name: n.Ident})
+	case *parse.FieldNode:
+		info.Fields = append(info.Fields, n.Ident[0])
+	case *parse.ChainNode:
+		collectFuncCalls(n.Node, info)
+	case *parse.IfNode:
+		collectBranchFuncCalls(&n.BranchNode, info)
+	case *parse.RangeNode:
+		collectBranchFuncCalls(&n.BranchNode, info)
+	case *parse.WithNode:
+		collectBranchFuncCalls(&n.BranchNode, info)
+	case *parse.TemplateNode:
+		collectFuncCalls(n.Pipe, info)
+	}
+}
+
+func collectBranchFuncCalls(n *parse.BranchNode, info *TemplateInfo) {
+	collectFuncCalls(n.Pipe, info)
+	collectFuncCalls(n.List, info)
+	collectFuncCalls(n.ElseList, info)
+}
+
+// WalkTemplateFields calls fn for every TemplateField in v with the path built from the yaml tags.
+func WalkTemplateFields(v interface{}, fn func(path string, field TemplateField)) {
+	walkTemplateFields(reflect.ValueOf(v), "", fn)
+}
+
+var templateFieldType = reflect.TypeOf(TemplateField{})
+
+func walkTemplateFields(v reflect.Value, path string, fn func(path string, field TemplateField)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkTemplateFields(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		if v.Type() == templateFieldType {
+			field := v.Interface().(TemplateField)
+			if field.IsDefined() {
+				fn(path, field)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				// unexported
+				continue
+			}
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			walkTemplateFields(v.Field(i), joinPath(path, name), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkTemplateFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkTemplateFields(iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())), fn)
+		}
+	}
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
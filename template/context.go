@@ -24,6 +24,16 @@ func (c *TemplateContext) Set(key string, value interface{}) {
 	c.variables[key] = value
 }
 
+// Get returns the variable set to the context or the parents, or nil if not set.
+func (c *TemplateContext) Get(key string) interface{} {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		if v, exist := ctx.variables[key]; exist {
+			return v
+		}
+	}
+	return nil
+}
+
 func (c *TemplateContext) AddFuncs(funcs map[string]interface{}) {
 	for k, v := range funcs {
 		if _, exist := c.funcs[k]; exist {
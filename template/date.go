@@ -0,0 +1,123 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeLayouts are tried by ParseTime after the given layouts, the epoch and the relative expressions.
+var DefaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+	time.UnixDate,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006年1月2日 15:04",
+	"2006年1月2日",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+var (
+	epochPattern    = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	relativePattern = regexp.MustCompile(`^(\d+|an?)\s*(second|sec|minute|min|hour|hr|day|week|month|year)s?\s+ago$`)
+)
+
+// ParseTime parses a time string written in the one of the layouts, the default layouts,
+// epoch seconds/milliseconds or a relative expression like "3 hours ago".
+// Times without time zone are parsed in the location.
+func ParseTime(value string, layouts []string, location *time.Location, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+	if location == nil {
+		location = time.Local
+	}
+
+	// the given layouts precede the epoch, since layouts like "20060102" are also digits
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+
+	if epochPattern.MatchString(value) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Epoch seconds exceed 1e11 after year 5138, so treat larger values as milliseconds.
+		if f >= 1e11 {
+			return time.UnixMilli(int64(f)).In(location), nil
+		}
+		return time.Unix(0, int64(f*1e9)).In(location), nil
+	}
+
+	if t, ok := parseRelativeTime(strings.ToLower(value), now.In(location)); ok {
+		return t, nil
+	}
+
+	for _, layout := range DefaultTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %s", value)
+}
+
+func parseRelativeTime(value string, now time.Time) (time.Time, bool) {
+	switch value {
+	case "now", "just now":
+		return now, true
+	case "today":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), true
+	case "yesterday":
+		return time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location()), true
+	}
+
+	matches := relativePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return time.Time{}, false
+	}
+	n := 1
+	if matches[1] != "a" && matches[1] != "an" {
+		n, _ = strconv.Atoi(matches[1])
+	}
+	switch matches[2] {
+	case "second", "sec":
+		return now.Add(-time.Duration(n) * time.Second), true
+	case "minute", "min":
+		return now.Add(-time.Duration(n) * time.Minute), true
+	case "hour", "hr":
+		return now.Add(-time.Duration(n) * time.Hour), true
+	case "day":
+		return now.AddDate(0, 0, -n), true
+	case "week":
+		return now.AddDate(0, 0, -7*n), true
+	case "month":
+		return now.AddDate(0, -n, 0), true
+	case "year":
+		return now.AddDate(-n, 0, 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,57 @@
+package template
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, jst)
+	tests := []struct {
+		name    string
+		value   string
+		layouts []string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "RFC3339", value: "2021-06-01T10:00:00Z", want: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "default layout in location", value: "2021/06/01 10:00", want: time.Date(2021, 6, 1, 10, 0, 0, 0, jst)},
+		{name: "trimmed", value: "  2021-06-01 ", want: time.Date(2021, 6, 1, 0, 0, 0, 0, jst)},
+		{name: "japanese", value: "2021年6月1日", want: time.Date(2021, 6, 1, 0, 0, 0, 0, jst)},
+		{name: "epoch seconds", value: "1622541600", want: time.Unix(1622541600, 0)},
+		{name: "epoch fraction", value: "1622541600.5", want: time.Unix(1622541600, 500000000)},
+		{name: "epoch milliseconds", value: "1622541600123", want: time.UnixMilli(1622541600123)},
+		{name: "layout precedes epoch", value: "20210601", layouts: []string{"20060102"}, want: time.Date(2021, 6, 1, 0, 0, 0, 0, jst)},
+		{name: "epoch without layout", value: "20210601", want: time.Unix(20210601, 0)},
+		{name: "layout precedes defaults", value: "01/06/2021", layouts: []string{"02/01/2006"}, want: time.Date(2021, 6, 1, 0, 0, 0, 0, jst)},
+		{name: "unmatched layout falls back", value: "2021-06-01", layouts: []string{"02/01/2006"}, want: time.Date(2021, 6, 1, 0, 0, 0, 0, jst)},
+		{name: "now", value: "just now", want: now},
+		{name: "today", value: "Today", want: time.Date(2021, 6, 15, 0, 0, 0, 0, jst)},
+		{name: "yesterday", value: "yesterday", want: time.Date(2021, 6, 14, 0, 0, 0, 0, jst)},
+		{name: "hours ago", value: "3 hours ago", want: now.Add(-3 * time.Hour)},
+		{name: "a day ago", value: "a day ago", want: now.AddDate(0, 0, -1)},
+		{name: "an hour ago", value: "an hour ago", want: now.Add(-time.Hour)},
+		{name: "weeks ago", value: "2 weeks ago", want: now.AddDate(0, 0, -14)},
+		{name: "abbreviation", value: "5 mins ago", want: now.Add(-5 * time.Minute)},
+		{name: "empty", value: " ", wantErr: true},
+		{name: "unsupported", value: "someday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, tt.layouts, jst, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q) failed: %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}